@@ -3,35 +3,56 @@ package app
 import (
 	"log"
 	"net/http"
+	"spotHero/app/exchange"
 	"spotHero/app/handler"
 	"spotHero/app/model"
+	"spotHero/app/pricing"
 	"spotHero/config"
 
 	"github.com/gorilla/mux"
 	"gorm.io/gorm"
 )
 
-// App has router and db instances
+// App has router, db, pricing engine and exchange rate provider instances
 type App struct {
-	Router *mux.Router
-	DB     *gorm.DB
+	Router            *mux.Router
+	DB                *gorm.DB
+	Pricing           *pricing.Engine
+	Exchange          exchange.Provider
+	SearchParallelism int
 }
 
 // Initialize initializes the app with predefined configuration
-func (a *App) Initialize(dbConfig *config.DBConfig) {
+func (a *App) Initialize(dbConfig *config.DBConfig, pricingConfig *config.PricingConfig) {
 
 	db, err := gorm.Open(dbConfig.GormDialect, &dbConfig.GormConfig)
 	if err != nil {
 		log.Fatal("Could not connect database", err)
 	}
 
-	_ = model.DBMigrate(db)
+	migrateErr := model.DBMigrate(db)
+	if migrateErr != nil {
+		log.Fatal("Could not migrate DB, error: ", migrateErr)
+	}
 	dataLoadErr := model.LoadRatesOnStart("rates.json" , db)
 	if dataLoadErr != nil {
 		log.Fatal("Could not load rate list in to DB, error: ", dataLoadErr)
 	}
 
+	policy, policyErr := pricing.ParsePolicy(pricingConfig.Policy)
+	if policyErr != nil {
+		log.Fatal("Could not configure pricing engine, error: ", policyErr)
+	}
+
+	exchangeRates, exchangeErr := exchange.NewFileProvider(pricingConfig.ExchangeRatesFile)
+	if exchangeErr != nil {
+		log.Fatal("Could not load exchange rates, error: ", exchangeErr)
+	}
+
 	a.DB = db
+	a.Pricing = pricing.NewEngine(policy)
+	a.Exchange = exchangeRates
+	a.SearchParallelism = pricingConfig.SearchParallelism
 	a.Router = mux.NewRouter()
 	a.setRouters()
 }
@@ -39,9 +60,55 @@ func (a *App) Initialize(dbConfig *config.DBConfig) {
 // setRouters sets the all required routers
 func (a *App) setRouters() {
 	// Routing for handling the projects
-	a.Get("/rates", a.handleRequest(handler.GetAllRates))
-	a.Put("/rates", a.handleRequest(handler.PutRate))
-	a.Get("/price", a.handleRequest(handler.GetPrice))
+	a.Get("/facilities", a.handleRequest(handler.GetFacilities))
+	a.Post("/facilities", a.handleRequest(handler.CreateFacility))
+	a.Get("/facilities/{id:[0-9]+}", a.handleRequest(handler.GetFacility))
+	a.Put("/facilities/{id:[0-9]+}", a.handleRequest(handler.PutFacility))
+	a.Delete("/facilities/{id:[0-9]+}", a.handleRequest(handler.DeleteFacility))
+
+	a.Get("/promos", a.handleRequest(handler.GetPromos))
+	a.Post("/promos", a.handleRequest(handler.CreatePromo))
+	a.Get("/promos/{id:[0-9]+}", a.handleRequest(handler.GetPromo))
+	a.Put("/promos/{id:[0-9]+}", a.handleRequest(handler.PutPromo))
+	a.Delete("/promos/{id:[0-9]+}", a.handleRequest(handler.DeletePromo))
+	a.Get("/taxes", a.handleRequest(handler.GetTaxes))
+	a.Post("/taxes", a.handleRequest(handler.CreateTax))
+	a.Get("/taxes/{id:[0-9]+}", a.handleRequest(handler.GetTax))
+	a.Put("/taxes/{id:[0-9]+}", a.handleRequest(handler.PutTax))
+	a.Delete("/taxes/{id:[0-9]+}", a.handleRequest(handler.DeleteTax))
+	a.Get("/search", a.handleRequest(handler.Search(a.Pricing, a.Exchange, a.SearchParallelism)))
+
+	// the facility routes, the routes without the facility prefix are of the default facility
+	for _, prefix := range []string{"", "/facilities/{facility:[0-9]+}"} {
+		a.Get(prefix+"/rates", a.handleRequest(handler.GetAllRates))
+		a.Put(prefix+"/rates", a.handleRequest(handler.PutRates))
+		a.Post(prefix+"/rates", a.handleRequest(handler.UpsertRate))
+		a.Get(prefix+"/rates/audit", a.handleRequest(handler.GetRateAudit))
+		a.Get(prefix+"/rates/scheduled", a.handleRequest(handler.GetScheduledRates))
+		a.Get(prefix+"/rates/{id:[0-9]+}", a.handleRequest(handler.GetRate))
+		a.Patch(prefix+"/rates/{id:[0-9]+}", a.handleRequest(handler.PatchRate))
+		a.Delete(prefix+"/rates/{id:[0-9]+}", a.handleRequest(handler.DeleteRate))
+		a.Get(prefix+"/caps", a.handleRequest(handler.GetCaps))
+		a.Put(prefix+"/caps", a.handleRequest(handler.PutCaps))
+		a.Get(prefix+"/surge", a.handleRequest(handler.GetSurge))
+		a.Put(prefix+"/surge", a.handleRequest(handler.PutSurge))
+		a.Get(prefix+"/products", a.handleRequest(handler.GetProducts))
+		a.Post(prefix+"/products", a.handleRequest(handler.CreateProduct))
+		a.Get(prefix+"/products/{id:[0-9]+}", a.handleRequest(handler.GetProduct))
+		a.Put(prefix+"/products/{id:[0-9]+}", a.handleRequest(handler.PutProduct))
+		a.Delete(prefix+"/products/{id:[0-9]+}", a.handleRequest(handler.DeleteProduct))
+		a.Get(prefix+"/price", a.handleRequest(handler.GetPrice(a.Pricing, a.Exchange)))
+		a.Get(prefix+"/reservations", a.handleRequest(handler.GetReservations))
+		a.Post(prefix+"/reservations", a.handleRequest(handler.CreateReservation(a.Pricing)))
+		a.Get(prefix+"/reservations/{id:[0-9]+}", a.handleRequest(handler.GetReservation))
+		a.Delete(prefix+"/reservations/{id:[0-9]+}", a.handleRequest(handler.DeleteReservation))
+		a.Get(prefix+"/calendar", a.handleRequest(handler.GetCalendar))
+		a.Put(prefix+"/calendar", a.handleRequest(handler.PutCalendar))
+		a.Post(prefix+"/calendar", a.handleRequest(handler.CreateCalendarOverride))
+		a.Get(prefix+"/calendar/{id:[0-9]+}", a.handleRequest(handler.GetCalendarOverride))
+		a.Put(prefix+"/calendar/{id:[0-9]+}", a.handleRequest(handler.PutCalendarOverride))
+		a.Delete(prefix+"/calendar/{id:[0-9]+}", a.handleRequest(handler.DeleteCalendarOverride))
+	}
 }
 
 // Get wraps the router for GET method
@@ -54,6 +121,21 @@ func (a *App) Put(path string, f func(w http.ResponseWriter, r *http.Request)) {
 	a.Router.HandleFunc(path, f).Methods("PUT")
 }
 
+// Post wraps the router for POST method
+func (a *App) Post(path string, f func(w http.ResponseWriter, r *http.Request)) {
+	a.Router.HandleFunc(path, f).Methods("POST")
+}
+
+// Patch wraps the router for PATCH method
+func (a *App) Patch(path string, f func(w http.ResponseWriter, r *http.Request)) {
+	a.Router.HandleFunc(path, f).Methods("PATCH")
+}
+
+// Delete wraps the router for DELETE method
+func (a *App) Delete(path string, f func(w http.ResponseWriter, r *http.Request)) {
+	a.Router.HandleFunc(path, f).Methods("DELETE")
+}
+
 // Run the app on it's router
 func (a *App) Run(host string) {
 	log.Fatal(http.ListenAndServe(host, a.Router))
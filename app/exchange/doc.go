@@ -0,0 +1,2 @@
+// Package exchange contains the exchange rate providers converting the prices between currencies.
+package exchange
@@ -0,0 +1,115 @@
+package exchange
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"sort"
+	"spotHero/app/model"
+)
+
+// ErrNoRate is returned when the provider has no exchange rate between the currencies, it's wrapped with the currencies.
+var ErrNoRate = errors.New("no exchange rate")
+
+// Rate is the exchange rate of one unit of the from currency in the to currency, as of the date of the provider source.
+type Rate struct {
+	From   model.Currency
+	To     model.Currency
+	Rate   float64
+	AsOf   string
+	Source string
+}
+
+// Convert returns the amount in the minor unit of the from currency converted to the minor unit of the to currency,
+// rounded half away from zero.
+func (r Rate) Convert(amount int) int {
+	scale := math.Pow10(r.To.MinorUnits() - r.From.MinorUnits())
+	return int(math.Round(float64(amount) * r.Rate * scale))
+}
+
+// Provider provides the exchange rates between the currencies, e.g. from a file or a remote service.
+type Provider interface {
+	// Rate returns the exchange rate from the currency to the other, or the ErrNoRate when it isn't known.
+	Rate(from, to model.Currency) (Rate, error)
+}
+
+// ratesFile is the wire format of the exchange rates file, the rates are the units of each currency for one unit of the base currency.
+type ratesFile struct {
+	Base  string             `json:"base"`
+	AsOf  string             `json:"as_of"`
+	Rates map[string]float64 `json:"rates"`
+}
+
+// FileProvider is the Provider of the exchange rates loaded from a json file, it works offline.
+// The rates between two currencies other than the base are crossed through the base currency.
+type FileProvider struct {
+	source string
+	base   model.Currency
+	asOf   string
+	rates  map[model.Currency]float64
+}
+
+// NewFileProvider reads & validates the exchange rates file.
+func NewFileProvider(path string) (*FileProvider, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	provider, err := ParseRates(data)
+	if err != nil {
+		return nil, fmt.Errorf("exchange rates file '%s': %w", path, err)
+	}
+	provider.source = path
+	return provider, nil
+}
+
+// ParseRates parse the exchange rates file content into the FileProvider, every currency must be ISO-4217 with a positive rate.
+func ParseRates(data []byte) (*FileProvider, error) {
+	var file ratesFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	validationErr := &model.ValidationError{}
+	base, err := model.ParseCurrency(file.Base)
+	if err != nil {
+		validationErr.Fields = append(validationErr.Fields, model.FieldError{Field: "base", Message: err.Error()})
+	}
+
+	provider := &FileProvider{base: base, asOf: file.AsOf, rates: map[model.Currency]float64{base: 1}}
+	codes := make([]string, 0, len(file.Rates))
+	for code := range file.Rates {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		rate, field := file.Rates[code], fmt.Sprintf("rates.%s", code)
+		currency, err := model.ParseCurrency(code)
+		if err != nil {
+			validationErr.Fields = append(validationErr.Fields, model.FieldError{Field: field, Message: err.Error()})
+			continue
+		}
+		if rate <= 0 {
+			validationErr.Fields = append(validationErr.Fields, model.FieldError{Field: field, Message: "must be positive"})
+			continue
+		}
+		provider.rates[currency] = rate
+	}
+
+	if len(validationErr.Fields) > 0 {
+		return nil, validationErr
+	}
+	return provider, nil
+}
+
+// Rate returns the exchange rate from the currency to the other, crossed through the base currency.
+func (p *FileProvider) Rate(from, to model.Currency) (Rate, error) {
+	fromRate, fromKnown := p.rates[from]
+	toRate, toKnown := p.rates[to]
+	if !fromKnown || !toKnown {
+		return Rate{}, fmt.Errorf("%w from %s to %s", ErrNoRate, from, to)
+	}
+	return Rate{From: from, To: to, Rate: toRate / fromRate, AsOf: p.asOf, Source: p.source}, nil
+}
@@ -0,0 +1,75 @@
+package exchange
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"os"
+	"path/filepath"
+	"spotHero/app/model"
+	"testing"
+)
+
+// Suite has the file provider of the exchange rates
+type Suite struct {
+	suite.Suite
+	provider *FileProvider
+}
+
+func (s *Suite) SetupSuite() {
+	path := filepath.Join(s.T().TempDir(), "exchange_rates.json")
+	require.NoError(s.T(), os.WriteFile(path, []byte(`{"base":"USD","as_of":"2015-07-01","rates":{"EUR":0.9,"JPY":120}}`), 0o600))
+
+	provider, err := NewFileProvider(path)
+	require.NoError(s.T(), err)
+	s.provider = provider
+}
+
+func TestInit(t *testing.T) {
+	suite.Run(t, new(Suite))
+}
+
+// TestRate test the rates from & to the base currency, and the cross rate through the base currency.
+func (s *Suite) TestRate() {
+	rate, err := s.provider.Rate("USD", "EUR")
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 0.9, rate.Rate)
+	assert.Equal(s.T(), "2015-07-01", rate.AsOf)
+	assert.Equal(s.T(), "exchange_rates.json", filepath.Base(rate.Source))
+
+	rate, err = s.provider.Rate("EUR", "USD")
+	require.NoError(s.T(), err)
+	assert.InDelta(s.T(), 1.1111, rate.Rate, 0.0001)
+
+	rate, err = s.provider.Rate("EUR", "JPY")
+	require.NoError(s.T(), err)
+	assert.InDelta(s.T(), 133.3333, rate.Rate, 0.0001)
+
+	_, err = s.provider.Rate("USD", "GBP")
+	assert.ErrorIs(s.T(), err, ErrNoRate)
+	assert.EqualError(s.T(), err, "no exchange rate from USD to GBP")
+}
+
+// TestConvert test the amount is converted between the minor units of the currencies, rounded to the minor unit.
+func (s *Suite) TestConvert() {
+	// 1500 cents of USD is 15 USD, 13.50 EUR
+	assert.Equal(s.T(), 1350, Rate{From: "USD", To: "EUR", Rate: 0.9}.Convert(1500))
+	// 15 USD is 1800 JPY, JPY has no minor unit
+	assert.Equal(s.T(), 1800, Rate{From: "USD", To: "JPY", Rate: 120}.Convert(1500))
+	assert.Equal(s.T(), 1250, Rate{From: "JPY", To: "USD", Rate: 1.0 / 120}.Convert(1500))
+	// 1999 cents at 0.9 is 1799.1 cents, rounded to 1799
+	assert.Equal(s.T(), 1799, Rate{From: "USD", To: "EUR", Rate: 0.9}.Convert(1999))
+}
+
+// TestParseRatesInvalid should return every invalid currency & rate of the file.
+func (s *Suite) TestParseRatesInvalid() {
+	_, err := ParseRates([]byte(`{"base":"usd","rates":{"EURO":0.9,"JPY":0}}`))
+	require.Error(s.T(), err)
+	assert.Equal(s.T(), []model.FieldError{
+		{Field: "rates.EURO", Message: "'EURO' isn't an ISO-4217 currency"},
+		{Field: "rates.JPY", Message: "must be positive"},
+	}, err.(*model.ValidationError).Fields)
+
+	_, err = NewFileProvider(filepath.Join(s.T().TempDir(), "missing.json"))
+	assert.Error(s.T(), err)
+}
@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"gorm.io/gorm"
+	"net/http"
+	"spotHero/app/model"
+	"strings"
+)
+
+const (
+	// ActorHeader is the request header naming the actor of the rate changes
+	ActorHeader = "X-Actor"
+	// RequestIDHeader is the request header with the request id, it's generated if missing and echoed in the response
+	RequestIDHeader = "X-Request-ID"
+	// anonymousActor is the actor of the request without the actor header
+	anonymousActor = "anonymous"
+)
+
+// newAuditor returns the auditor of the request actor & id, the request id is set on the response header.
+func newAuditor(w http.ResponseWriter, r *http.Request) model.Auditor {
+	auditor := model.Auditor{Actor: strings.TrimSpace(r.Header.Get(ActorHeader)), RequestID: strings.TrimSpace(r.Header.Get(RequestIDHeader))}
+	if auditor.Actor == "" {
+		auditor.Actor = anonymousActor
+	}
+	if auditor.RequestID == "" {
+		id := make([]byte, 16)
+		_, _ = rand.Read(id)
+		auditor.RequestID = hex.EncodeToString(id)
+	}
+	w.Header().Set(RequestIDHeader, auditor.RequestID)
+	return auditor
+}
+
+// GetRateAudit api endpoints to get the rate audit entries of the facility in the order of the changes, filtered by the query params:
+// from & to (ISO-8601 time range), rate key (days, times & tz), rate_id, action, actor, limit & offset.
+func GetRateAudit(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
+	facility, ok := facilityID(db, w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	filtered := db.Model(&model.RateAudit{}).Where("facility_id = ?", facility)
+
+	for _, bound := range []struct{ param, condition string }{{"from", "at >= ?"}, {"to", "at < ?"}} {
+		if query.Get(bound.param) == "" {
+			continue
+		}
+		boundTime, err := validateTimeParam(r.URL, bound.param)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, ErrCodeInvalidParam, err.Error())
+			return
+		}
+		filtered = filtered.Where(bound.condition, boundTime.UTC())
+	}
+
+	if days := query.Get("days"); days != "" {
+		weekdays, err := model.ParseWeekdays(days)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, ErrCodeInvalidParam, fmt.Sprintf("Url param 'days' is invalid, %s ", err.Error()))
+			return
+		}
+		filtered = filtered.Where("days = ?", weekdays)
+	}
+
+	if times := query.Get("times"); times != "" {
+		window, err := model.ParseTimeWindow(times)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, ErrCodeInvalidParam, fmt.Sprintf("Url param 'times' is invalid, %s ", err.Error()))
+			return
+		}
+		filtered = filtered.Where("start_time = ? AND end_time = ?", window.Start, window.End)
+	}
+
+	for _, column := range []string{"tz", "action", "actor"} {
+		if value := query.Get(column); value != "" {
+			filtered = filtered.Where(column+" = ?", value)
+		}
+	}
+
+	rateID, err := intParam(query, "rate_id", 0, 1, -1)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, ErrCodeInvalidParam, err.Error())
+		return
+	}
+	if rateID > 0 {
+		filtered = filtered.Where("rate_id = ?", rateID)
+	}
+
+	limit, err := intParam(query, "limit", defaultRatesLimit, 1, maxRatesLimit)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, ErrCodeInvalidParam, err.Error())
+		return
+	}
+	offset, err := intParam(query, "offset", 0, 0, -1)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, ErrCodeInvalidParam, err.Error())
+		return
+	}
+
+	audits := []model.RateAudit{}
+	if err := filtered.Order("id").Limit(limit).Offset(offset).Find(&audits).Error; err != nil {
+		respondError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, audits)
+}
@@ -0,0 +1,218 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"io"
+	"net/http"
+	"spotHero/app/model"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// GetCalendar api endpoints to get the calendar overrides of the facility, ordered by the start date.
+func GetCalendar(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
+	facility, ok := facilityID(db, w, r)
+	if !ok {
+		return
+	}
+
+	overrides := []model.CalendarOverride{}
+	if err := db.Where("facility_id = ?", facility).Order("start_date").Find(&overrides).Error; err != nil {
+		respondError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, model.Calendar{Overrides: overrides})
+}
+
+// PutCalendar api endpoints to import the calendar override list of the facility, replacing all its overrides within a single transaction.
+func PutCalendar(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
+	facility, ok := facilityID(db, w, r)
+	if !ok {
+		return
+	}
+	calendarInput := model.CalendarInput{}
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&calendarInput); err != nil {
+		respondError(w, http.StatusBadRequest, ErrCodeInvalidBody, err.Error())
+		return
+	}
+
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+
+		}
+	}(r.Body)
+
+	overrides, validationErr := calendarInput.Validate()
+	if validationErr != nil {
+		respondValidationError(w, validationErr.(*model.ValidationError))
+		return
+	}
+
+	for i := range overrides {
+		overrides[i].FacilityID = facility
+	}
+
+	// rejecting the override list having overrides on the same date
+	if overlapErr := model.CheckCalendarOverlaps(overrides); overlapErr != nil {
+		respondError(w, http.StatusConflict, ErrCodeConflict, overlapErr.Error())
+		return
+	}
+
+	replaceErr := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("facility_id = ?", facility).Delete(&model.CalendarOverride{}).Error; err != nil {
+			return err
+		}
+		for i := range overrides {
+			if err := tx.Create(&overrides[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	if replaceErr != nil {
+		respondError(w, http.StatusInternalServerError, ErrCodeInternal, replaceErr.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, model.Calendar{Overrides: overrides})
+}
+
+// CreateCalendarOverride api endpoints to add the calendar override to the facility, it can't share a date with a stored override of the facility.
+func CreateCalendarOverride(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
+	facility, ok := facilityID(db, w, r)
+	if !ok {
+		return
+	}
+
+	override, ok := decodeCalendarOverride(w, r)
+	if !ok {
+		return
+	}
+	override.FacilityID = facility
+	saveCalendarOverride(db, w, override, http.StatusCreated)
+}
+
+// GetCalendarOverride api endpoints to get the calendar override of the facility by id.
+func GetCalendarOverride(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
+	override := getCalendarOverrideOr404(db, w, r)
+	if override == nil {
+		return
+	}
+	respondJSON(w, http.StatusOK, override)
+}
+
+// PutCalendarOverride api endpoints to replace the calendar override of the facility by id.
+func PutCalendarOverride(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
+	stored := getCalendarOverrideOr404(db, w, r)
+	if stored == nil {
+		return
+	}
+
+	override, ok := decodeCalendarOverride(w, r)
+	if !ok {
+		return
+	}
+	override.ID, override.FacilityID = stored.ID, stored.FacilityID
+	saveCalendarOverride(db, w, override, http.StatusOK)
+}
+
+// DeleteCalendarOverride api endpoints to delete the calendar override of the facility by id.
+func DeleteCalendarOverride(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
+	override := getCalendarOverrideOr404(db, w, r)
+	if override == nil {
+		return
+	}
+
+	if err := db.Delete(override).Error; err != nil {
+		respondError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodeCalendarOverride decodes & validates the calendar override of the request body, or respond the error otherwise
+func decodeCalendarOverride(w http.ResponseWriter, r *http.Request) (model.CalendarOverride, bool) {
+	overrideInput := model.CalendarOverrideInput{}
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&overrideInput); err != nil {
+		respondError(w, http.StatusBadRequest, ErrCodeInvalidBody, err.Error())
+		return model.CalendarOverride{}, false
+	}
+
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+
+		}
+	}(r.Body)
+
+	override, validationErr := overrideInput.Validate()
+	if validationErr != nil {
+		respondValidationError(w, validationErr.(*model.ValidationError))
+		return model.CalendarOverride{}, false
+	}
+	return override, true
+}
+
+// saveCalendarOverride saves the calendar override within a transaction, rejecting it if it shares a date with any other override of the facility.
+func saveCalendarOverride(db *gorm.DB, w http.ResponseWriter, override model.CalendarOverride, status int) {
+	saveErr := db.Transaction(func(tx *gorm.DB) error {
+		var overlapping []model.CalendarOverride
+		if err := tx.Where("facility_id = ? AND start_date <= ? AND end_date >= ?", override.FacilityID, override.EndDate, override.StartDate).Find(&overlapping).Error; err != nil {
+			return err
+		}
+		if err := model.CheckCalendarOverlap(override, overlapping); err != nil {
+			return err
+		}
+		return tx.Save(&override).Error
+	})
+
+	var overlapErr *model.CalendarOverlapError
+	if errors.As(saveErr, &overlapErr) {
+		respondError(w, http.StatusConflict, ErrCodeConflict, overlapErr.Error())
+		return
+	}
+
+	if saveErr != nil {
+		respondError(w, http.StatusInternalServerError, ErrCodeInternal, saveErr.Error())
+		return
+	}
+
+	respondJSON(w, status, override)
+}
+
+// getCalendarOverrideOr404 gets the calendar override of the facility & id path params if exists, or respond the 404 error otherwise
+func getCalendarOverrideOr404(db *gorm.DB, w http.ResponseWriter, r *http.Request) *model.CalendarOverride {
+	facility, ok := facilityID(db, w, r)
+	if !ok {
+		return nil
+	}
+
+	id, parseErr := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if parseErr != nil {
+		respondError(w, http.StatusNotFound, ErrCodeNotFound, fmt.Sprintf("calendar override '%s' not found", mux.Vars(r)["id"]))
+		return nil
+	}
+
+	override := model.CalendarOverride{}
+	if err := db.Where("facility_id = ?", facility).First(&override, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(w, http.StatusNotFound, ErrCodeNotFound, fmt.Sprintf("calendar override '%d' not found", id))
+		} else {
+			respondError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error())
+		}
+		return nil
+	}
+	return &override
+}
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"bytes"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"spotHero/app/model"
+	"spotHero/app/pricing"
+)
+
+// TestPutCalendar test to import the calendar override list, replacing the stored overrides.
+func (s *Suite) TestPutCalendar(){
+	s.expectFacility(1)
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `calendar_overrides` WHERE facility_id = ?")).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectExec("INSERT INTO `calendar_overrides`(.*)").
+		WithArgs(1, "July 4th", "2015-07-04", "2015-07-04", 1.5, nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	s.mock.ExpectExec("INSERT INTO `calendar_overrides`(.*)").
+		WithArgs(1, "Stadium", "2015-07-10", "2015-07-11", 0.0, `[{"days":"mon,tues,wed,thurs,fri,sat,sun","times":"1700-2400","tz":"America/Chicago","price":4000}]`).
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	s.mock.ExpectCommit()
+
+	body := `{"overrides":[{"name":"July 4th","start_date":"2015-07-04","multiplier":1.5},` +
+		`{"name":"Stadium","start_date":"2015-07-10","end_date":"2015-07-11","rates":[{"times":"1700-2400","tz":"America/Chicago","price":4000}]}]}`
+	req, err := http.NewRequest("PUT", "/calendar", bytes.NewBufferString(body))
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+	PutCalendar(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusOK)
+	assert.Equal(s.T(), httpRec.Body.String(), `{"overrides":[{"id":1,"name":"July 4th","start_date":"2015-07-04","end_date":"2015-07-04","multiplier":1.5},`+
+		`{"id":2,"name":"Stadium","start_date":"2015-07-10","end_date":"2015-07-11","rates":[{"days":"mon,tues,wed,thurs,fri,sat,sun","times":"1700-2400","tz":"America/Chicago","price":4000}]}]}`)
+}
+
+// TestPutCalendarOverlap should respond 409 for the overrides sharing a date.
+func (s *Suite) TestPutCalendarOverlap(){
+	s.expectFacility(1)
+	body := `{"overrides":[{"name":"July 4th","start_date":"2015-07-04","multiplier":1.5},` +
+		`{"name":"Weekend","start_date":"2015-07-03","end_date":"2015-07-05","multiplier":1.2}]}`
+	req, err := http.NewRequest("PUT", "/calendar", bytes.NewBufferString(body))
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+	PutCalendar(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusConflict)
+	assert.Equal(s.T(), httpRec.Body.String(), `{"code":"conflict","error":"override 'Weekend' (2015-07-03 to 2015-07-05) overlaps override 'July 4th' (2015-07-04 to 2015-07-04)"}`)
+}
+
+// TestCreateCalendarOverrideOverlap should respond 409 for the override sharing a date with a stored override.
+func (s *Suite) TestCreateCalendarOverrideOverlap(){
+	s.expectFacility(1)
+	s.mock.ExpectBegin()
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `calendar_overrides` WHERE facility_id = ? AND start_date <= ? AND end_date >= ?")).
+		WithArgs(1, "2015-07-05", "2015-07-03").
+		WillReturnRows(s.mock.NewRows(overrideColumns).AddRow(1, 1, "July 4th", "2015-07-04", "2015-07-04", 1.5, nil))
+	s.mock.ExpectRollback()
+
+	req, err := http.NewRequest("POST", "/calendar", bytes.NewBufferString(`{"name":"Weekend","start_date":"2015-07-03","end_date":"2015-07-05","multiplier":1.2}`))
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+	CreateCalendarOverride(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusConflict)
+}
+
+// TestGetFacilityCalendar get the calendar overrides of the facility only.
+func (s *Suite) TestGetFacilityCalendar(){
+	s.expectFacility(2)
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `calendar_overrides` WHERE facility_id = ? ORDER BY start_date")).
+		WithArgs(2).
+		WillReturnRows(s.mock.NewRows(overrideColumns).AddRow(3, 2, "Stadium", "2015-07-10", "2015-07-11", 1.5, nil))
+
+	req, err := http.NewRequest("GET", "/facilities/2/calendar", nil)
+	assert.NoError(s.T(), err)
+	req = mux.SetURLVars(req, map[string]string{"facility": "2"})
+	httpRec := httptest.NewRecorder()
+	GetCalendar(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusOK)
+	assert.Equal(s.T(), httpRec.Body.String(), `{"overrides":[{"id":3,"name":"Stadium","start_date":"2015-07-10","end_date":"2015-07-11","multiplier":1.5}]}`)
+}
+
+// TestCreateCalendarOverrideInvalid should respond 422 with every invalid field of the override.
+func (s *Suite) TestCreateCalendarOverrideInvalid(){
+	s.expectFacility(1)
+	req, err := http.NewRequest("POST", "/calendar", bytes.NewBufferString(`{"name":"July 4th","start_date":"07/04/2015","multiplier":1.5,"rates":[{"times":"1700-2400","tz":"America/Chicago","price":4000}]}`))
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+	CreateCalendarOverride(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusUnprocessableEntity)
+	assert.Equal(s.T(), httpRec.Body.String(), `{"code":"validation_failed","error":"validation failed","fields":[`+
+		`{"field":"start_date","message":"'07/04/2015' isn't as per YYYY-MM-DD format"},{"field":"multiplier","message":"isn't allowed with the rates"}]}`)
+}
+
+// TestDeleteCalendarOverrideNotFound should respond 404 for the unknown override id.
+func (s *Suite) TestDeleteCalendarOverrideNotFound(){
+	s.expectFacility(1)
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `calendar_overrides` WHERE facility_id = ? AND `calendar_overrides`.`id` = ?")).
+		WithArgs(1, 9).
+		WillReturnRows(s.mock.NewRows(overrideColumns))
+
+	req, err := http.NewRequest("DELETE", "/calendar/9", nil)
+	assert.NoError(s.T(), err)
+	req = mux.SetURLVars(req, map[string]string{"id": "9"})
+	httpRec := httptest.NewRecorder()
+	DeleteCalendarOverride(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusNotFound)
+	assert.Equal(s.T(), httpRec.Body.String(), `{"code":"not_found","error":"calendar override '9' not found"}`)
+}
+
+// TestGetPriceCalendarMultiplier price the July 4th stay with the weekday rate multiplied by the calendar override.
+func (s *Suite) TestGetPriceCalendarMultiplier(){
+	s.expectFacility(1)
+	s.expectTariff(testTariff{
+		Rates: []model.Rate{rate(s, "fri,sat,sun", "0900-2100", "America/Chicago", 2000)},
+		Overrides: []model.CalendarOverride{{Name: "July 4th", StartDate: "2015-07-04", EndDate: "2015-07-04", Multiplier: 1.5}},
+	})
+
+	req, err := http.NewRequest("GET", "/price?start=2015-07-04T15:00:00%2B00:00&end=2015-07-04T20:00:00%2B00:00", nil)
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+
+	GetPrice(pricing.NewEngine(pricing.PolicyStrict), testExchange)(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusOK)
+	assert.Equal(s.T(), httpRec.Body.String(), `{"price":{"amount":3000,"currency":"USD"},"total":{"amount":3000,"currency":"USD"}}`)
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"encoding/json"
+	"gorm.io/gorm"
+	"io"
+	"net/http"
+	"spotHero/app/model"
+)
+
+// GetCaps api endpoints to get the price caps of the facility, zero caps are disabled.
+func GetCaps(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
+	facility, ok := facilityID(db, w, r)
+	if !ok {
+		return
+	}
+
+	caps := model.PriceCaps{}
+	if err := db.Where("id = ?", facility).Limit(1).Find(&caps).Error; err != nil {
+		respondError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, caps)
+}
+
+// PutCaps api endpoints to replace the price caps of the facility.
+func PutCaps(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
+	facility, ok := facilityID(db, w, r)
+	if !ok {
+		return
+	}
+
+	caps := model.PriceCaps{}
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&caps); err != nil {
+		respondError(w, http.StatusBadRequest, ErrCodeInvalidBody, err.Error())
+		return
+	}
+
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+
+		}
+	}(r.Body)
+
+	if validationErr := caps.Validate(); validationErr != nil {
+		respondValidationError(w, validationErr.(*model.ValidationError))
+		return
+	}
+
+	// the caps are stored as the single row of the facility, keyed on the facility id
+	caps.ID = facility
+	if err := db.Save(&caps).Error; err != nil {
+		respondError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, caps)
+}
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"bytes"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"spotHero/app/model"
+)
+
+// TestGetCaps test the GetCaps endpoint.
+func (s *Suite) TestGetCaps(){
+	s.expectFacility(1)
+	s.expectCaps(model.PriceCaps{DailyMax: 3000, MinCharge: 500})
+
+	req, err := http.NewRequest("GET", "/caps", nil)
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+	GetCaps(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusOK)
+	assert.Equal(s.T(), httpRec.Body.String(), `{"daily_max":3000,"weekly_max":0,"min_charge":500}`)
+}
+
+// TestPutCaps test the caps are stored as the single row.
+func (s *Suite) TestPutCaps(){
+	s.expectFacility(1)
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec(regexp.QuoteMeta("UPDATE `price_caps` SET")).
+		WithArgs(3000, 15000, 0, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectCommit()
+
+	req, err := http.NewRequest("PUT", "/caps", bytes.NewBufferString(`{"daily_max":3000,"weekly_max":15000,"min_charge":0}`))
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+	PutCaps(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusOK)
+	assert.Equal(s.T(), httpRec.Body.String(), `{"daily_max":3000,"weekly_max":15000,"min_charge":0}`)
+}
+
+// TestPutCapsInvalid should respond 422 for the negative caps.
+func (s *Suite) TestPutCapsInvalid(){
+	s.expectFacility(1)
+	req, err := http.NewRequest("PUT", "/caps", bytes.NewBufferString(`{"daily_max":-1}`))
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+	PutCaps(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusUnprocessableEntity)
+}
+
+// TestGetCapsNoDefaultFacility should respond 404 for the route without a facility when the default facility isn't stored.
+func (s *Suite) TestGetCapsNoDefaultFacility(){
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `facilities` WHERE `facilities`.`id` = ?")).
+		WithArgs(1).
+		WillReturnRows(s.mock.NewRows(facilityColumns))
+
+	req, err := http.NewRequest("GET", "/caps", nil)
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+	GetCaps(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusNotFound)
+	assert.Equal(s.T(), httpRec.Body.String(), `{"code":"not_found","error":"facility '1' not found"}`)
+}
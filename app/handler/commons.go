@@ -3,6 +3,7 @@ package handler
 import (
 	"encoding/json"
 	"net/http"
+	"spotHero/app/model"
 )
 
 // respondJSON makes the response with payload as json format
@@ -24,7 +25,39 @@ func respondJSON(w http.ResponseWriter, status int, payload interface{}) {
 	}
 }
 
+// Error codes of the ErrorResponse.
+const (
+	// ErrCodeInvalidParam is the code for the missing or invalid url param
+	ErrCodeInvalidParam = "invalid_param"
+	// ErrCodeInvalidBody is the code for the request body which can't be decoded
+	ErrCodeInvalidBody = "invalid_body"
+	// ErrCodeValidationFailed is the code for the request body having invalid fields
+	ErrCodeValidationFailed = "validation_failed"
+	// ErrCodeNotFound is the code for the unknown resource
+	ErrCodeNotFound = "not_found"
+	// ErrCodeConflict is the code for the write conflicting with the stored data
+	ErrCodeConflict = "conflict"
+	// ErrCodeUnavailable is the code for the stay which can't be priced
+	ErrCodeUnavailable = "unavailable"
+	// ErrCodeInvalidPromo is the code for the unknown promo code or the promo code which can't be applied
+	ErrCodeInvalidPromo = "invalid_promo"
+	// ErrCodeInternal is the code for the unexpected server error
+	ErrCodeInternal = "internal_error"
+)
+
+// ErrorResponse is the error payload shared by every api endpoint.
+type ErrorResponse struct {
+	Code   string             `json:"code"`
+	Error  string             `json:"error"`
+	Fields []model.FieldError `json:"fields,omitempty"`
+}
+
 // respondError makes the error response with payload as json format
-func respondError(w http.ResponseWriter, code int, message string) {
-	respondJSON(w, code, map[string]string{"error": message})
-}
\ No newline at end of file
+func respondError(w http.ResponseWriter, status int, code string, message string) {
+	respondJSON(w, status, ErrorResponse{Code: code, Error: message})
+}
+
+// respondValidationError makes the unprocessable entity response with the invalid fields as json format
+func respondValidationError(w http.ResponseWriter, validationErr *model.ValidationError) {
+	respondJSON(w, http.StatusUnprocessableEntity, ErrorResponse{Code: ErrCodeValidationFailed, Error: "validation failed", Fields: validationErr.Fields})
+}
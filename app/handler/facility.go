@@ -0,0 +1,215 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"io"
+	"net/http"
+	"spotHero/app/model"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// GetFacilities api endpoints to get the facilities stored in the database.
+func GetFacilities(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
+	facilities := []model.Facility{}
+	if err := db.Order("id").Find(&facilities).Error; err != nil {
+		respondError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, facilities)
+}
+
+// CreateFacility api endpoints to add the facility, its rates are added with the /facilities/{id}/rates endpoints.
+func CreateFacility(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
+	facility, ok := decodeFacility(w, r)
+	if !ok {
+		return
+	}
+
+	if err := db.Create(&facility).Error; err != nil {
+		respondError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusCreated, facility)
+}
+
+// GetFacility api endpoints to get the facility by id.
+func GetFacility(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
+	facility := getFacilityOr404(db, w, r)
+	if facility == nil {
+		return
+	}
+	respondJSON(w, http.StatusOK, facility)
+}
+
+// PutFacility api endpoints to replace the facility by id.
+func PutFacility(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
+	stored := getFacilityOr404(db, w, r)
+	if stored == nil {
+		return
+	}
+
+	facility, ok := decodeFacility(w, r)
+	if !ok {
+		return
+	}
+	facility.ID = stored.ID
+
+	if err := db.Save(&facility).Error; err != nil {
+		respondError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, facility)
+}
+
+// DeleteFacility api endpoints to delete the facility by id, with its caps, surge, products, calendar overrides & tax rules.
+// The facility having rates or reservations can't be deleted.
+func DeleteFacility(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
+	facility := getFacilityOr404(db, w, r)
+	if facility == nil {
+		return
+	}
+
+	deleteErr := db.Transaction(func(tx *gorm.DB) error {
+		var rateCount int64
+		if err := tx.Model(&model.Rate{}).Where("facility_id = ?", facility.ID).Count(&rateCount).Error; err != nil {
+			return err
+		}
+		if rateCount > 0 {
+			return errFacilityHasRates
+		}
+		var reservationCount int64
+		if err := tx.Model(&model.Reservation{}).Where("facility_id = ?", facility.ID).Count(&reservationCount).Error; err != nil {
+			return err
+		}
+		if reservationCount > 0 {
+			return errFacilityHasReservations
+		}
+		if err := tx.Delete(&model.PriceCaps{}, facility.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&model.Surge{}, facility.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("facility_id = ?", facility.ID).Delete(&model.Product{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("facility_id = ?", facility.ID).Delete(&model.CalendarOverride{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("facility_id = ?", facility.ID).Delete(&model.TaxRule{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(facility).Error
+	})
+
+	if errors.Is(deleteErr, errFacilityHasRates) {
+		respondError(w, http.StatusConflict, ErrCodeConflict, fmt.Sprintf("facility '%d' has rates, delete them first", facility.ID))
+		return
+	}
+
+	if errors.Is(deleteErr, errFacilityHasReservations) {
+		respondError(w, http.StatusConflict, ErrCodeConflict, fmt.Sprintf("facility '%d' has reservations, cancel them first", facility.ID))
+		return
+	}
+
+	if deleteErr != nil {
+		respondError(w, http.StatusInternalServerError, ErrCodeInternal, deleteErr.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// errFacilityHasRates is returned on deleting the facility having rates.
+var errFacilityHasRates = errors.New("facility has rates")
+
+// errFacilityHasReservations is returned on deleting the facility having reservations, they would be of the next facility of its id otherwise.
+var errFacilityHasReservations = errors.New("facility has reservations")
+
+// decodeFacility decodes & validates the facility of the request body, or respond the error otherwise
+func decodeFacility(w http.ResponseWriter, r *http.Request) (model.Facility, bool) {
+	facilityInput := model.FacilityInput{}
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&facilityInput); err != nil {
+		respondError(w, http.StatusBadRequest, ErrCodeInvalidBody, err.Error())
+		return model.Facility{}, false
+	}
+
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+
+		}
+	}(r.Body)
+
+	if facilityInput.Rates != nil {
+		respondError(w, http.StatusBadRequest, ErrCodeInvalidBody, "facility 'rates' are added with the /facilities/{id}/rates endpoints")
+		return model.Facility{}, false
+	}
+
+	facility, validationErr := facilityInput.Validate()
+	if validationErr != nil {
+		respondValidationError(w, validationErr.(*model.ValidationError))
+		return model.Facility{}, false
+	}
+	facility.ID = 0
+	return facility, true
+}
+
+// getFacilityOr404 gets the facility of the id path param if exists, or respond the 404 error otherwise
+func getFacilityOr404(db *gorm.DB, w http.ResponseWriter, r *http.Request) *model.Facility {
+	return findFacilityOr404(db, w, mux.Vars(r)["id"])
+}
+
+// facilityID returns the id of the facility path param, responding the 404 error for the unknown facility.
+// The routes without the facility path param are of the default facility, which must exist as well.
+func facilityID(db *gorm.DB, w http.ResponseWriter, r *http.Request) (uint, bool) {
+	param, isPresent := mux.Vars(r)["facility"]
+	if !isPresent {
+		param = strconv.Itoa(model.DefaultFacilityID)
+	}
+
+	facility := findFacilityOr404(db, w, param)
+	if facility == nil {
+		return 0, false
+	}
+	return facility.ID, true
+}
+
+// facilityCurrency returns the currency of the facility prices, the facility without a currency is in the default currency.
+func facilityCurrency(db *gorm.DB, facility uint) (model.Currency, error) {
+	var currencies []model.Currency
+	if err := db.Model(&model.Facility{}).Where("id = ?", facility).Limit(1).Pluck("currency", &currencies).Error; err != nil {
+		return "", err
+	}
+	if len(currencies) == 0 || currencies[0] == "" {
+		return model.DefaultCurrency, nil
+	}
+	return currencies[0], nil
+}
+
+// findFacilityOr404 gets the facility of the id if exists, or respond the 404 error otherwise
+func findFacilityOr404(db *gorm.DB, w http.ResponseWriter, param string) *model.Facility {
+	id, parseErr := strconv.ParseUint(param, 10, 64)
+	if parseErr != nil {
+		respondError(w, http.StatusNotFound, ErrCodeNotFound, fmt.Sprintf("facility '%s' not found", param))
+		return nil
+	}
+
+	facility := model.Facility{}
+	if err := db.First(&facility, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(w, http.StatusNotFound, ErrCodeNotFound, fmt.Sprintf("facility '%d' not found", id))
+		} else {
+			respondError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error())
+		}
+		return nil
+	}
+	return &facility
+}
@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"bytes"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"spotHero/app/model"
+	"spotHero/app/pricing"
+	"time"
+)
+
+// facilityColumns are the columns of the facilities table.
+var facilityColumns = []string{"id", "name", "address", "tz", "capacity", "currency"}
+
+// expectFacility expects the facility query of the id, returning the facility.
+func (s *Suite) expectFacility(id int) {
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `facilities` WHERE `facilities`.`id` = ?")).
+		WithArgs(id).
+		WillReturnRows(s.mock.NewRows(facilityColumns).AddRow(id, "Wrigley", "1060 W Addison St", "America/Chicago", 120, "USD"))
+}
+
+// TestCreateFacility test the CreateFacility endpoint.
+func (s *Suite) TestCreateFacility(){
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec("INSERT INTO `facilities`(.*)").
+		WithArgs("Wrigley", "1060 W Addison St", "America/Chicago", 120, "", "USD").
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	s.mock.ExpectCommit()
+
+	req, err := http.NewRequest("POST", "/facilities", bytes.NewBufferString(`{"name":"Wrigley","address":"1060 W Addison St","tz":"America/Chicago","capacity":120,"currency":"USD"}`))
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+	CreateFacility(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusCreated)
+	assert.Equal(s.T(), httpRec.Body.String(), `{"id":2,"name":"Wrigley","address":"1060 W Addison St","tz":"America/Chicago","capacity":120,"currency":"USD"}`)
+}
+
+// TestCreateFacilityInvalid should respond 422 with every invalid field of the facility.
+func (s *Suite) TestCreateFacilityInvalid(){
+	req, err := http.NewRequest("POST", "/facilities", bytes.NewBufferString(`{"name":" ","tz":"America/Springfield","capacity":-1}`))
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+	CreateFacility(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusUnprocessableEntity)
+	assert.Equal(s.T(), httpRec.Body.String(), `{"code":"validation_failed","error":"validation failed","fields":[{"field":"name","message":"is required"},`+
+		`{"field":"tz","message":"'America/Springfield' isn't an IANA time zone"},{"field":"capacity","message":"can't be negative"}]}`)
+}
+
+// TestGetFacilityNotFound should respond 404 for the unknown facility id.
+func (s *Suite) TestGetFacilityNotFound(){
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `facilities` WHERE `facilities`.`id` = ?")).
+		WithArgs(9).
+		WillReturnRows(s.mock.NewRows(facilityColumns))
+
+	req, err := http.NewRequest("GET", "/facilities/9", nil)
+	assert.NoError(s.T(), err)
+	req = mux.SetURLVars(req, map[string]string{"id": "9"})
+	httpRec := httptest.NewRecorder()
+	GetFacility(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusNotFound)
+	assert.Equal(s.T(), httpRec.Body.String(), `{"code":"not_found","error":"facility '9' not found"}`)
+}
+
+// TestDeleteFacilityWithRates should respond 409 for the facility having rates.
+func (s *Suite) TestDeleteFacilityWithRates(){
+	s.expectFacility(2)
+	s.mock.ExpectBegin()
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM `rates` WHERE facility_id = ?")).
+		WithArgs(2).
+		WillReturnRows(s.mock.NewRows([]string{"count"}).AddRow(3))
+	s.mock.ExpectRollback()
+
+	req, err := http.NewRequest("DELETE", "/facilities/2", nil)
+	assert.NoError(s.T(), err)
+	req = mux.SetURLVars(req, map[string]string{"id": "2"})
+	httpRec := httptest.NewRecorder()
+	DeleteFacility(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusConflict)
+	assert.Equal(s.T(), httpRec.Body.String(), `{"code":"conflict","error":"facility '2' has rates, delete them first"}`)
+}
+
+// TestDeleteFacilityWithReservations should respond 409 for the facility having reservations, the id of the deleted facility isn't reused.
+func (s *Suite) TestDeleteFacilityWithReservations(){
+	db, err := gorm.Open(sqlite.Open(filepath.Join(s.T().TempDir(), "rates.db")), &gorm.Config{})
+	require.NoError(s.T(), err)
+	require.NoError(s.T(), model.DBMigrate(db))
+	for i := 1; i <= 3; i++ {
+		require.NoError(s.T(), db.Create(&model.Facility{Name: "Lot", Tz: "America/Chicago", Capacity: 50}).Error)
+	}
+	start := time.Date(2015, time.July, 2, 15, 0, 0, 0, time.UTC)
+	require.NoError(s.T(), db.Create(&model.Reservation{FacilityID: 3, Start: start, End: start.Add(time.Hour), Vehicle: model.VehicleStandard}).Error)
+
+	deleteFacility := func(id string) *httptest.ResponseRecorder {
+		req, err := http.NewRequest("DELETE", "/facilities/"+id, nil)
+		assert.NoError(s.T(), err)
+		req = mux.SetURLVars(req, map[string]string{"id": id})
+		httpRec := httptest.NewRecorder()
+		DeleteFacility(db, httpRec, req)
+		return httpRec
+	}
+
+	httpRec := deleteFacility("3")
+	assert.Equal(s.T(), httpRec.Code, http.StatusConflict)
+	assert.Equal(s.T(), httpRec.Body.String(), `{"code":"conflict","error":"facility '3' has reservations, cancel them first"}`)
+
+	require.NoError(s.T(), db.Where("facility_id = ?", 3).Delete(&model.Reservation{}).Error)
+	assert.Equal(s.T(), deleteFacility("3").Code, http.StatusNoContent)
+
+	facility := model.Facility{Name: "New lot", Tz: "America/Chicago", Capacity: 50}
+	require.NoError(s.T(), db.Create(&facility).Error)
+	assert.Equal(s.T(), uint(4), facility.ID)
+}
+
+// TestGetFacilityPrice test the price is quoted with the rates & caps of the facility.
+func (s *Suite) TestGetFacilityPrice(){
+	s.expectFacility(2)
+	s.expectTariff(testTariff{
+		Facility: 2,
+		Rates: []model.Rate{rate(s, "fri,sat,sun", "0900-2100", "America/Chicago", 2000)},
+		Caps: model.PriceCaps{DailyMax: 1800},
+	})
+
+	req, err := http.NewRequest("GET", "/facilities/2/price?start=2015-07-04T15:00:00%2B00:00&end=2015-07-04T20:00:00%2B00:00", nil)
+	assert.NoError(s.T(), err)
+	req = mux.SetURLVars(req, map[string]string{"facility": "2"})
+	httpRec := httptest.NewRecorder()
+
+	GetPrice(pricing.NewEngine(pricing.PolicyStrict), testExchange)(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusOK)
+	assert.Equal(s.T(), httpRec.Body.String(), `{"price":{"amount":1800,"currency":"USD"},"total":{"amount":1800,"currency":"USD"}}`)
+}
+
+// TestGetFacilityRatesNotFound should respond 404 for the rates of the unknown facility.
+func (s *Suite) TestGetFacilityRatesNotFound(){
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `facilities` WHERE `facilities`.`id` = ?")).
+		WithArgs(9).
+		WillReturnRows(s.mock.NewRows(facilityColumns))
+
+	req, err := http.NewRequest("GET", "/facilities/9/rates", nil)
+	assert.NoError(s.T(), err)
+	req = mux.SetURLVars(req, map[string]string{"facility": "9"})
+	httpRec := httptest.NewRecorder()
+	GetAllRates(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusNotFound)
+	assert.Equal(s.T(), httpRec.Body.String(), `{"code":"not_found","error":"facility '9' not found"}`)
+}
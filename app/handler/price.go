@@ -7,60 +7,268 @@ import (
 	"gorm.io/gorm"
 	"net/http"
 	"net/url"
+	"spotHero/app/exchange"
 	"spotHero/app/model"
+	"spotHero/app/pricing"
 	"strconv"
-	"strings"
 	"time"
 )
 
-// Price contains the price for response.
+// Price contains the price for response, with the taxes & fees charged on it and the total, and the breakdown in detail mode.
+// The amounts are in the facility currency, or converted to the requested currency with the exchange rate used.
 type Price struct {
-	Price int `json:"price"`
+	Price     model.Money    `json:"price"`
+	Taxes     []PriceTax     `json:"taxes,omitempty"`
+	Total     model.Money    `json:"total"`
+	Exchange  *PriceExchange `json:"exchange,omitempty"`
+	Breakdown *Breakdown     `json:"breakdown,omitempty"`
 }
 
-// GetPrice return the price based on query start and end time param
-func GetPrice(db *gorm.DB, w http.ResponseWriter, r *http.Request){
-	startTime, startErr := validateTimeParam(r.URL, "start")
-	endTime, endErr := validateTimeParam(r.URL, "end")
+// PriceTax is the tax or fee charged on top of the price.
+type PriceTax struct {
+	Name   string      `json:"name"`
+	Amount model.Money `json:"amount"`
+}
+
+// PriceExchange is the exchange rate converting the price from the facility currency, as of the date of its source.
+type PriceExchange struct {
+	From   model.Currency `json:"from"`
+	To     model.Currency `json:"to"`
+	Rate   float64        `json:"rate"`
+	AsOf   string         `json:"as_of,omitempty"`
+	Source string         `json:"source,omitempty"`
+}
+
+// Breakdown itemizes the price into the matched rate segments and the adjustments applied on their sum,
+// or the product pricing the stay along with the rate segments & subtotal it beats. The amounts are in the facility currency.
+type Breakdown struct {
+	Currency    model.Currency    `json:"currency"`
+	Product     *model.Product    `json:"product,omitempty"`
+	Segments    []PriceSegment    `json:"segments"`
+	Subtotal    int               `json:"subtotal"`
+	Adjustments []PriceAdjustment `json:"adjustments"`
+	Gaps        []PriceGap        `json:"gaps,omitempty"`
+}
+
+// PriceSegment is the part of the stay priced by one rate, start & end are in the rate's time zone.
+// Minutes are the billed minutes, rounded to the increments of the increment & tiered rates.
+type PriceSegment struct {
+	Days     string    `json:"days"`
+	Times    string    `json:"times"`
+	Tz       string    `json:"tz"`
+	Kind     string    `json:"kind"`
+	Override string    `json:"override,omitempty"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Minutes  int       `json:"minutes"`
+	Amount   int       `json:"amount"`
+}
+
+// PriceAdjustment is the change applied on the subtotal, e.g. by the pricing policy.
+type PriceAdjustment struct {
+	Name   string `json:"name"`
+	Amount int    `json:"amount"`
+}
 
-	if startErr != nil {
-		respondError(w, http.StatusOK, startErr.Error())
-		return
+// PriceGap is the part of the stay not covered by any rate, it isn't charged.
+type PriceGap struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// newPrice returns the price of the quote in the facility currency, converted with the exchange rate if not nil.
+// The converted total is the sum of the converted price & taxes.
+func newPrice(quote *pricing.Quote, currency model.Currency, rate *exchange.Rate) Price {
+	money := func(amount int) model.Money {
+		if rate == nil {
+			return model.Money{Amount: amount, Currency: currency}
+		}
+		return model.Money{Amount: rate.Convert(amount), Currency: rate.To}
 	}
 
-	if endErr != nil {
-		respondError(w, http.StatusOK, endErr.Error())
-		return
+	price := Price{Price: money(quote.Price)}
+	total := price.Price.Amount
+	for _, tax := range quote.Taxes {
+		line := PriceTax{Name: tax.Name, Amount: money(tax.Amount)}
+		price.Taxes = append(price.Taxes, line)
+		total += line.Amount.Amount
 	}
+	price.Total = model.Money{Amount: total, Currency: price.Price.Currency}
+	if rate != nil {
+		price.Exchange = &PriceExchange{From: rate.From, To: rate.To, Rate: rate.Rate, AsOf: rate.AsOf, Source: rate.Source}
+	}
+	return price
+}
 
-	timeDifference := int(endTime.Sub(*startTime).Hours())
-	if timeDifference > 24 || timeDifference < 0 {
-		respondJSON(w, http.StatusOK, "unavailable")
-		return
+// newBreakdown returns the breakdown of the quote, the amounts are in the facility currency.
+func newBreakdown(quote *pricing.Quote, currency model.Currency) *Breakdown {
+	breakdown := &Breakdown{
+		Currency:    currency,
+		Product:     quote.Product,
+		Segments:    make([]PriceSegment, len(quote.Segments)),
+		Subtotal:    quote.Subtotal(),
+		Adjustments: make([]PriceAdjustment, len(quote.Adjustments)),
+	}
+	for i, segment := range quote.Segments {
+		breakdown.Segments[i] = PriceSegment{
+			Days:     segment.Rate.Days.String(),
+			Times:    segment.Rate.Window().String(),
+			Tz:       segment.Rate.Tz,
+			Kind:     string(segment.Rate.Kind),
+			Override: segment.Override,
+			Start:    segment.Start,
+			End:      segment.End,
+			Minutes:  int((segment.Billed + time.Minute - 1) / time.Minute),
+			Amount:   segment.Price,
+		}
+	}
+	for i, adjustment := range quote.Adjustments {
+		breakdown.Adjustments[i] = PriceAdjustment{Name: adjustment.Name, Amount: adjustment.Amount}
+	}
+	for _, gap := range quote.Gaps {
+		breakdown.Gaps = append(breakdown.Gaps, PriceGap{Start: gap.Start, End: gap.End})
 	}
+	return breakdown
+}
 
-	loc, _ := time.LoadLocation("America/Chicago")
+// GetPrice return the price handler which quotes the query start and end time param against the facility rates with the pricing engine,
+// the rate prices are multiplied for the vehicle param (standard by default) and the price is discounted by the promo param if any.
+// The price is converted to the currency param if any, with the exchange rate of the provider.
+func GetPrice(engine *pricing.Engine, provider exchange.Provider) func(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
+	return func(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
+		facility, ok := facilityID(db, w, r)
+		if !ok {
+			return
+		}
 
-	// getting the rates from the database
-	var obRates []model.Rate
-	dayRune:= []rune(startTime.Weekday().String())
-	day := "%" + strings.ToLower(string(dayRune[0:2])) +"%"
-	db.Where("days like ? AND tz =?", day, loc.String()).Find(&obRates)
+		startTime, startErr := validateTimeParam(r.URL, "start")
+		endTime, endErr := validateTimeParam(r.URL, "end")
 
-	// finding the correct price as per the stored rates.
-	for _, rate := range obRates {
-		rStartTime, rEndTime, err := handleRateTimes(rate)
-		if err != nil {
-			respondJSON(w, http.StatusOK, "unavailable")
+		if startErr != nil {
+			respondError(w, http.StatusBadRequest, ErrCodeInvalidParam, startErr.Error())
+			return
+		}
+
+		if endErr != nil {
+			respondError(w, http.StatusBadRequest, ErrCodeInvalidParam, endErr.Error())
+			return
+		}
+
+		detail, detailErr := validateBoolParam(r.URL, "detail")
+		if detailErr != nil {
+			respondError(w, http.StatusBadRequest, ErrCodeInvalidParam, detailErr.Error())
+			return
+		}
+
+		if endTime.Before(*startTime) {
+			respondError(w, http.StatusBadRequest, ErrCodeInvalidParam, "Url param 'end' is before 'start' ")
+			return
+		}
+
+		vehicle, vehicleErr := validateVehicleParam(r.URL)
+		if vehicleErr != nil {
+			respondError(w, http.StatusBadRequest, ErrCodeInvalidParam, vehicleErr.Error())
+			return
 		}
 
-		if startTime.Hour() >= *rStartTime && endTime.Hour() <= *rEndTime {
-			respondJSON(w, http.StatusOK, Price{rate.Price})
+		target, currencyErr := validateCurrencyParam(r.URL)
+		if currencyErr != nil {
+			respondError(w, http.StatusBadRequest, ErrCodeInvalidParam, currencyErr.Error())
 			return
 		}
+
+		var promo *model.PromoCode
+		if code := r.URL.Query().Get("promo"); code != "" {
+			var promoErr error
+			if promo, promoErr = findPromo(db, code, facility, time.Now()); promoErr != nil {
+				respondPromoError(w, promoErr)
+				return
+			}
+		}
+
+		// splitting the stay into segments across days & rate windows, pricing them and applying the caps.
+		quote, err := quoteFacility(db, engine, facility, vehicle, promo, *startTime, *endTime)
+		if errors.Is(err, pricing.ErrUnavailable) {
+			respondError(w, http.StatusUnprocessableEntity, ErrCodeUnavailable, err.Error())
+			return
+		}
+
+		if err != nil {
+			respondPromoError(w, err)
+			return
+		}
+		currency := quote.Currency
+
+		// converting the price from the facility currency with the exchange rate of the provider
+		var rate *exchange.Rate
+		if target != "" && target != currency {
+			exchangeRate, rateErr := provider.Rate(currency, target)
+			if errors.Is(rateErr, exchange.ErrNoRate) {
+				respondError(w, http.StatusUnprocessableEntity, ErrCodeUnavailable, rateErr.Error())
+				return
+			}
+			if rateErr != nil {
+				respondError(w, http.StatusInternalServerError, ErrCodeInternal, rateErr.Error())
+				return
+			}
+			rate = &exchangeRate
+		}
+
+		price := newPrice(quote, currency, rate)
+		if detail {
+			price.Breakdown = newBreakdown(quote, currency)
+		}
+		respondJSON(w, http.StatusOK, price)
+	}
+}
+
+// quoteFacility quotes the stay of the vehicle class against the facility rates, caps, products & the calendar overrides of the stay dates with the pricing engine,
+// surged by the facility occupancy if the surge is enabled and discounted by the promo code if not nil. The tax rules of the facility
+// & of its jurisdiction are charged on top of the price, the quote is in the facility currency.
+func quoteFacility(db *gorm.DB, engine *pricing.Engine, facility uint, vehicle model.VehicleClass, promo *model.PromoCode, startTime, endTime time.Time) (*pricing.Quote, error) {
+	// getting the facility rates & caps from the database, each rate is matched in its own time zone by the pricing engine
+	tariff := pricing.Tariff{Vehicle: vehicle, Promo: promo}
+	if err := db.Where("facility_id = ?", facility).Find(&tariff.Rates).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Where("id = ?", facility).Limit(1).Find(&tariff.Caps).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Where("facility_id = ?", facility).Find(&tariff.Products).Error; err != nil {
+		return nil, err
 	}
 
-	respondJSON(w, http.StatusOK, "unavailable")
+	jurisdiction := db.Model(&model.Facility{}).Select("jurisdiction").Where("id = ? AND jurisdiction <> ''", facility)
+	if err := db.Where("facility_id = ? OR jurisdiction IN (?)", facility, jurisdiction).Order("id").Find(&tariff.Taxes).Error; err != nil {
+		return nil, err
+	}
+
+	// the occupancy surge reads the reservations of the stay, only when the surge of the facility is enabled
+	surge := model.Surge{}
+	if err := db.Where("id = ?", facility).Limit(1).Find(&surge).Error; err != nil {
+		return nil, err
+	}
+	if surge.Enabled {
+		stored := model.Facility{}
+		if err := db.First(&stored, facility).Error; err != nil {
+			return nil, err
+		}
+		tariff.Adjusters = append(tariff.Adjusters, pricing.OccupancySurge{Surge: surge, Capacity: stored.Capacity, Occupancy: occupancyCounter(db, facility)})
+	}
+
+	// getting the facility calendar overrides of the stay dates, a day apart on both sides covers the dates in any time zone
+	firstDate, lastDate := startTime.UTC().AddDate(0, 0, -1).Format(model.DateFormat), endTime.UTC().AddDate(0, 0, 1).Format(model.DateFormat)
+	if err := db.Where("facility_id = ? AND start_date <= ? AND end_date >= ?", facility, lastDate, firstDate).Find(&tariff.Overrides).Error; err != nil {
+		return nil, err
+	}
+
+	currency, err := facilityCurrency(db, facility)
+	if err != nil {
+		return nil, err
+	}
+	tariff.Currency = currency
+
+	return engine.Quote(tariff, startTime, endTime)
 }
 
 // validateTimeParam validate the time param from the http request query
@@ -85,29 +293,39 @@ func validateTimeParam(url *url.URL, paramName string) (*time.Time, error){
 	return &parsedTime, parsErr
 }
 
-// handleRateTimes handle the time string value of the rate model for processing.
-func handleRateTimes(rate model.Rate) (*int, *int, error) {
-	times := rate.Times
-	splitTime := strings.SplitAfter(times,"-") // format: "start-end", split time strings into two parts
+// validateVehicleParam validate the optional vehicle class param from the http request query, missing param is the standard vehicle
+func validateVehicleParam(url *url.URL) (model.VehicleClass, error) {
+	vehicle, err := model.ParseVehicleClass(url.Query().Get("vehicle"))
+	if err != nil {
+		return "", fmt.Errorf("Url param 'vehicle' is invalid, %s ", err.Error())
+	}
+	return vehicle, nil
+}
 
-	// after split, it should be into two parts.
-	if len(splitTime) != 2 {
-		return nil, nil, errors.New("time value is not as per the standard")
+// validateCurrencyParam validate the optional ISO-4217 currency param from the http request query, missing param is the facility currency
+func validateCurrencyParam(url *url.URL) (model.Currency, error) {
+	param := url.Query().Get("currency")
+	if param == "" {
+		return "", nil
 	}
 
-	// trim the '0' and parse the value into int for start hour
-	startTimeString := strings.Trim(strings.TrimSuffix(splitTime[0], "-"), "0")
-	parsedStartTime, err := strconv.Atoi(startTimeString)
+	currency, err := model.ParseCurrency(param)
 	if err != nil {
-		return nil, nil, err
+		return "", fmt.Errorf("Url param 'currency' is invalid, %s ", err.Error())
 	}
+	return currency, nil
+}
 
-	// trim the '0' and parse the value into int for end hour
-	endTimeString := strings.Trim(splitTime[1], "0")
-	parsedEndTime, err := strconv.Atoi(endTimeString)
-	if err != nil{
-		return nil, nil, err
+// validateBoolParam validate the optional bool param from the http request query, missing param is false
+func validateBoolParam(url *url.URL, paramName string) (bool, error) {
+	param := url.Query().Get(paramName)
+	if param == "" {
+		return false, nil
 	}
 
-	return &parsedStartTime, &parsedEndTime, nil
-}
\ No newline at end of file
+	value, parseErr := strconv.ParseBool(param)
+	if parseErr != nil {
+		return false, fmt.Errorf("Url param '%s' isn't a boolean ", paramName)
+	}
+	return value, nil
+}
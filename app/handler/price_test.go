@@ -5,48 +5,17 @@ import (
 	"github.com/stretchr/testify/assert"
 	"net/http"
 	"net/http/httptest"
-	"regexp"
+	"spotHero/app/exchange"
+	"spotHero/app/model"
+	"spotHero/app/pricing"
 )
 
-// TestStandardHandleRateTimes test the start and end time as per correct format.
-func (s *Suite) TestStandardHandleRateTimes(){
-	start, end, err := handleRateTimes(s.rate)
-	assert.NoError(s.T(), err)
-	assert.Equal(s.T(), *start, 9)
-	assert.Equal(s.T(), *end, 21)
-}
-
-// TestNotStandardHandleRateTimes should throw error for incorrect format.
-func (s *Suite) TestNotStandardHandleRateTimes(){
-	s.rate.Times = "09002100"
-	start, end, err := handleRateTimes(s.rate)
-	assert.Error(s.T(), err)
-	assert.Equal(s.T(), err.Error(), "time value is not as per the standard")
-	assert.Nil(s.T(), start)
-	assert.Nil(s.T(), end)
-	s.rate.Times = "0900-2100"
-}
-
-// TestNonParsableStartHandleRateTimes should throw error for non-parsable start hour.
-func (s *Suite) TestNonParsableStartHandleRateTimes(){
-	s.rate.Times = "0a00-2100"
-	start, end, err := handleRateTimes(s.rate)
-	assert.Error(s.T(), err)
-	assert.Equal(s.T(), err.Error(), "strconv.Atoi: parsing \"a\": invalid syntax")
-	assert.Nil(s.T(), start)
-	assert.Nil(s.T(), end)
-	s.rate.Times = "0900-2100"
-}
+// testExchange is the exchange rate provider of the price tests.
+var testExchange, _ = exchange.ParseRates([]byte(`{"base":"USD","as_of":"2015-07-01","rates":{"EUR":0.9,"JPY":120}}`))
 
-// TestNonParsableEndHandleRateTimes should throw error for non-parsable start hour.
-func (s *Suite) TestNonParsableEndHandleRateTimes(){
-	s.rate.Times = "0900-2Z00"
-	start, end, err := handleRateTimes(s.rate)
-	assert.Error(s.T(), err)
-	assert.Equal(s.T(), err.Error(), "strconv.Atoi: parsing \"2Z\": invalid syntax")
-	assert.Nil(s.T(), start)
-	assert.Nil(s.T(), end)
-	s.rate.Times = "0900-2100"
+// usd returns the amount in cents of USD.
+func usd(amount int) model.Money {
+	return model.Money{Amount: amount, Currency: "USD"}
 }
 
 // TestValidateTimeStartParam should return the valid 'start' time object
@@ -101,17 +70,17 @@ func (s *Suite) TestValidateTimeNotParsableIso8601Time(){
 
 // TestGetPriceValidPrice1500 return valid 1500 price for the query.
 func (s *Suite) TestGetPriceValidPrice1500(){
-	rows := s.mock.NewRows([]string{"days", "times", "tz", "price"}).AddRow(s.rate.Days, s.rate.Times, s.rate.Tz, s.rate.Price)
-	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `rates`")).WillReturnRows(rows)
+	s.expectFacility(1)
+	s.expectTariff(testTariff{Rates: []model.Rate{s.rate}})
 
-	req, err := http.NewRequest("GET", "/price?start=2015-07-04T15:00:00-05:00&end=2015-07-04T20:00:00-05:00", nil)
+	req, err := http.NewRequest("GET", "/price?start=2015-07-02T15:00:00-05:00&end=2015-07-02T20:00:00-05:00", nil)
 	assert.NoError(s.T(), err)
 	httpRec := httptest.NewRecorder()
 
-	GetPrice(s.DB, httpRec, req)
+	GetPrice(pricing.NewEngine(pricing.PolicyStrict), testExchange)(s.DB, httpRec, req)
 	assert.Equal(s.T(), httpRec.Code, http.StatusOK)
 
-	price := Price{Price: 1500}
+	price := Price{Price: usd(1500), Total: usd(1500)}
 	jsonPrice, marshalError := json.Marshal(price)
 	assert.NoError(s.T(), marshalError)
 	assert.Equal(s.T(), httpRec.Body.String(), string(jsonPrice) )
@@ -119,19 +88,17 @@ func (s *Suite) TestGetPriceValidPrice1500(){
 
 // TestGetPriceValidPrice1750 return valid 1750 price for the query.
 func (s *Suite) TestGetPriceValidPrice1750(){
-	rows := s.mock.NewRows([]string{"days", "times", "tz", "price"}).
-		AddRow(s.rate.Days, s.rate.Times, s.rate.Tz, s.rate.Price).
-		AddRow("wed", "0600-1800", "America/Chicago", 1750)
-	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `rates`")).WillReturnRows(rows)
+	s.expectFacility(1)
+	s.expectTariff(testTariff{Rates: []model.Rate{s.rate, rate(s, "wed", "0600-1800", "America/Chicago", 1750)}})
 
 	req, err := http.NewRequest("GET", "/price?start=2015-07-01T07:00:00-05:00&end=2015-07-01T12:00:00-05:00", nil)
 	assert.NoError(s.T(), err)
 	httpRec := httptest.NewRecorder()
 
-	GetPrice(s.DB, httpRec, req)
+	GetPrice(pricing.NewEngine(pricing.PolicyStrict), testExchange)(s.DB, httpRec, req)
 	assert.Equal(s.T(), httpRec.Code, http.StatusOK)
 
-	price := Price{Price: 1750}
+	price := Price{Price: usd(1750), Total: usd(1750)}
 	jsonPrice, marshalError := json.Marshal(price)
 	assert.NoError(s.T(), marshalError)
 	assert.Equal(s.T(), httpRec.Body.String(), string(jsonPrice) )
@@ -139,16 +106,239 @@ func (s *Suite) TestGetPriceValidPrice1750(){
 
 // TestGetPriceUnavailable return Unavailable price for the query.
 func (s *Suite) TestGetPriceUnavailable(){
-	rows := s.mock.NewRows([]string{"days", "times", "tz", "price"}).
-		AddRow(s.rate.Days, s.rate.Times, s.rate.Tz, s.rate.Price).
-		AddRow("wed", "0600-1800", "America/Chicago", 1750)
-	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `rates`")).WillReturnRows(rows)
+	s.expectFacility(1)
+	s.expectTariff(testTariff{Rates: []model.Rate{s.rate, rate(s, "wed", "0600-1800", "America/Chicago", 1750)}})
 
 	req, err := http.NewRequest("GET", "/price?start=2015-07-04T07:00:00%2B05:00&end=2015-07-04T20:00:00%2B05:00", nil)
 	assert.NoError(s.T(), err)
 	httpRec := httptest.NewRecorder()
 
-	GetPrice(s.DB, httpRec, req)
+	GetPrice(pricing.NewEngine(pricing.PolicyStrict), testExchange)(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusUnprocessableEntity)
+	assert.Equal(s.T(), httpRec.Body.String(), `{"code":"unavailable","error":"unavailable: no rate covers the stay"}`)
+}
+
+// TestGetPriceOvernight return the summed price for the stay crossing midnight, the sum policy skips 00:00-01:00 gap.
+func (s *Suite) TestGetPriceOvernight(){
+	s.expectFacility(1)
+	s.expectTariff(testTariff{
+		Rates: []model.Rate{rate(s, "fri,sat,sun", "0900-2100", "America/Chicago", 2000), rate(s, "fri", "2100-2400", "America/Chicago", 500), rate(s, "sat", "0100-0900", "America/Chicago", 800)},
+	})
+
+	req, err := http.NewRequest("GET", "/price?start=2015-07-03T22:00:00-05:00&end=2015-07-04T08:00:00-05:00", nil)
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+
+	GetPrice(pricing.NewEngine(pricing.PolicySum), testExchange)(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusOK)
+
+	price := Price{Price: usd(1300), Total: usd(1300)}
+	jsonPrice, marshalError := json.Marshal(price)
+	assert.NoError(s.T(), marshalError)
+	assert.Equal(s.T(), httpRec.Body.String(), string(jsonPrice) )
+}
+
+// TestGetPriceOffsetConvertedToRateZone return the price of the rate matched on the Chicago wall-clock time of a +05:00 request.
+func (s *Suite) TestGetPriceOffsetConvertedToRateZone(){
+	s.expectFacility(1)
+	s.expectTariff(testTariff{Rates: []model.Rate{rate(s, "fri,sat,sun", "0900-2100", "America/Chicago", 2000)}})
+
+	// sat 19:00-23:00 +05:00 is sat 09:00-13:00 Chicago
+
+	req, err := http.NewRequest("GET", "/price?start=2015-07-04T19:00:00%2B05:00&end=2015-07-04T23:00:00%2B05:00", nil)
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+
+	GetPrice(pricing.NewEngine(pricing.PolicyStrict), testExchange)(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusOK)
+
+	price := Price{Price: usd(2000), Total: usd(2000)}
+	jsonPrice, marshalError := json.Marshal(price)
+	assert.NoError(s.T(), marshalError)
+	assert.Equal(s.T(), httpRec.Body.String(), string(jsonPrice) )
+}
+
+// TestGetPriceInvalidParams should respond 400 for the missing, non ISO-8601 or reversed start & end params.
+func (s *Suite) TestGetPriceInvalidParams(){
+	for query, message := range map[string]string{
+		"end=2015-07-01T07:00:00-05:00":                               "missing Url Param 'start' ",
+		"start=2015-07-01T07:00:00-05:00&end=noon":                    "Url param 'end' isn't as per ISO-8601 standard ",
+		"start=2015-07-01T12:00:00-05:00&end=2015-07-01T07:00:00-05:00": "Url param 'end' is before 'start' ",
+	} {
+		s.expectFacility(1)
+		req, err := http.NewRequest("GET", "/price?"+query, nil)
+		assert.NoError(s.T(), err)
+		httpRec := httptest.NewRecorder()
+
+		GetPrice(pricing.NewEngine(pricing.PolicyStrict), testExchange)(s.DB, httpRec, req)
+		assert.Equal(s.T(), httpRec.Code, http.StatusBadRequest)
+
+		jsonError, marshalError := json.Marshal(ErrorResponse{Code: ErrCodeInvalidParam, Error: message})
+		assert.NoError(s.T(), marshalError)
+		assert.Equal(s.T(), httpRec.Body.String(), string(jsonError))
+	}
+}
+
+// TestGetPriceMultiDay return the price of the stay longer than 24 hours with the daily max applied.
+func (s *Suite) TestGetPriceMultiDay(){
+	s.expectFacility(1)
+	s.expectTariff(testTariff{
+		Rates: []model.Rate{rate(s, "mon,tues,wed,thurs,fri,sat,sun", "0000-2400", "America/Chicago", 2500)},
+		Caps: model.PriceCaps{DailyMax: 4000},
+	})
+
+	// wed 12:00 -> sat 12:00, four day windows: two in the first 24 hours, one in each later 24 hours
+
+	req, err := http.NewRequest("GET", "/price?start=2015-07-01T12:00:00-05:00&end=2015-07-04T12:00:00-05:00&detail=true", nil)
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+
+	GetPrice(pricing.NewEngine(pricing.PolicyStrict), testExchange)(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusOK)
+
+	var price Price
+	assert.NoError(s.T(), json.Unmarshal(httpRec.Body.Bytes(), &price))
+	assert.Equal(s.T(), price.Price, usd(9000))
+	assert.Equal(s.T(), price.Breakdown.Subtotal, 10000)
+	assert.Equal(s.T(), price.Breakdown.Adjustments, []PriceAdjustment{{Name: "daily max", Amount: -1000}})
+}
+
+// TestGetPriceLongerThanMaxStay should respond 422 for the stay longer than 31 days.
+func (s *Suite) TestGetPriceLongerThanMaxStay(){
+	s.expectFacility(1)
+	s.expectTariff(testTariff{})
+
+	req, err := http.NewRequest("GET", "/price?start=2015-07-01T07:00:00-05:00&end=2015-08-01T07:01:00-05:00", nil)
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+
+	GetPrice(pricing.NewEngine(pricing.PolicyStrict), testExchange)(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusUnprocessableEntity)
+	assert.Equal(s.T(), httpRec.Body.String(), `{"code":"unavailable","error":"unavailable: stay is longer than 31 days"}`)
+}
+
+// TestGetPriceDetail return the price breakdown with the matched rate segments & adjustments.
+func (s *Suite) TestGetPriceDetail(){
+	s.expectFacility(1)
+	s.expectTariff(testTariff{
+		Rates: []model.Rate{rate(s, "fri", "2100-2400", "America/Chicago", 500), rate(s, "sat", "0100-0900", "America/Chicago", 800)},
+	})
+
+	req, err := http.NewRequest("GET", "/price?start=2015-07-03T22:00:00-05:00&end=2015-07-04T08:30:00-05:00&detail=true", nil)
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+
+	GetPrice(pricing.NewEngine(pricing.PolicyMax), testExchange)(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusOK)
+	assert.JSONEq(s.T(), `{"price":{"amount":800,"currency":"USD"},"total":{"amount":800,"currency":"USD"},"breakdown":{
+		"currency":"USD",
+		"segments":[
+			{"days":"fri","times":"2100-2400","tz":"America/Chicago","kind":"flat","start":"2015-07-03T22:00:00-05:00","end":"2015-07-04T00:00:00-05:00","minutes":120,"amount":500},
+			{"days":"sat","times":"0100-0900","tz":"America/Chicago","kind":"flat","start":"2015-07-04T01:00:00-05:00","end":"2015-07-04T08:30:00-05:00","minutes":450,"amount":800}
+		],
+		"subtotal":1300,
+		"adjustments":[{"name":"max policy","amount":-500}],
+		"gaps":[{"start":"2015-07-04T00:00:00-05:00","end":"2015-07-04T01:00:00-05:00"}]
+	}}`, httpRec.Body.String())
+}
+
+// TestGetPriceTiered return the price of the tiered rate, first hour price & the rate price for every hour after.
+func (s *Suite) TestGetPriceTiered(){
+	s.expectFacility(1)
+	tiered := rate(s, "wed", "0600-1800", "America/Chicago", 300)
+	tiered.Kind, tiered.Increment, tiered.Rounding = model.KindTiered, 60, model.RoundUp
+	tiered.Tiers = model.Tiers{{Minutes: 60, Price: 500}}
+	s.expectTariff(testTariff{Rates: []model.Rate{tiered}})
+
+	req, err := http.NewRequest("GET", "/price?start=2015-07-01T07:00:00-05:00&end=2015-07-01T09:30:00-05:00", nil)
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+
+	GetPrice(pricing.NewEngine(pricing.PolicyStrict), testExchange)(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusOK)
+	assert.Equal(s.T(), httpRec.Body.String(), `{"price":{"amount":1100,"currency":"USD"},"total":{"amount":1100,"currency":"USD"}}`)
+}
+
+// TestGetPriceInvalidDetail should respond 400 for the non boolean detail param.
+func (s *Suite) TestGetPriceInvalidDetail(){
+	s.expectFacility(1)
+	req, err := http.NewRequest("GET", "/price?start=2015-07-01T07:00:00-05:00&end=2015-07-01T12:00:00-05:00&detail=full", nil)
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+
+	GetPrice(pricing.NewEngine(pricing.PolicyStrict), testExchange)(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusBadRequest)
+}
+
+// TestGetPriceVehicle return the price of the rate multiplied for the vehicle param.
+func (s *Suite) TestGetPriceVehicle(){
+	s.expectFacility(1)
+	oversized := s.rate
+	oversized.Vehicles = model.VehicleMultipliers{model.VehicleOversize: 1.5}
+	s.expectTariff(testTariff{Rates: []model.Rate{oversized}})
+
+	req, err := http.NewRequest("GET", "/price?start=2015-07-02T15:00:00-05:00&end=2015-07-02T20:00:00-05:00&vehicle=oversize", nil)
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+
+	GetPrice(pricing.NewEngine(pricing.PolicyStrict), testExchange)(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusOK)
+	assert.Equal(s.T(), httpRec.Body.String(), `{"price":{"amount":2250,"currency":"USD"},"total":{"amount":2250,"currency":"USD"}}`)
+}
+
+// TestGetPriceUnknownVehicle should respond 400 for the unknown vehicle param.
+func (s *Suite) TestGetPriceUnknownVehicle(){
+	s.expectFacility(1)
+	req, err := http.NewRequest("GET", "/price?start=2015-07-02T15:00:00-05:00&end=2015-07-02T20:00:00-05:00&vehicle=bus", nil)
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+
+	GetPrice(pricing.NewEngine(pricing.PolicyStrict), testExchange)(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusBadRequest)
+	assert.Equal(s.T(), httpRec.Body.String(), `{"code":"invalid_param","error":"Url param 'vehicle' is invalid, unknown vehicle 'bus', isn't compact, standard, oversize or motorcycle "}`)
+}
+
+// TestGetPriceCurrency return the price & taxes converted to the currency param with the exchange rate used, JPY has no minor unit.
+func (s *Suite) TestGetPriceCurrency(){
+	s.expectFacility(1)
+	s.expectTariff(testTariff{
+		Rates: []model.Rate{s.rate},
+		Taxes: []model.TaxRule{{ID: 1, Name: "Chicago parking tax", Kind: model.TaxPercent, BasisPoints: 2375, Jurisdiction: "chicago"}},
+	})
+
+	req, err := http.NewRequest("GET", "/price?start=2015-07-02T15:00:00-05:00&end=2015-07-02T20:00:00-05:00&currency=jpy", nil)
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+
+	GetPrice(pricing.NewEngine(pricing.PolicyStrict), testExchange)(s.DB, httpRec, req)
 	assert.Equal(s.T(), httpRec.Code, http.StatusOK)
-	assert.Equal(s.T(), httpRec.Body.String(), "\"unavailable\"" )
+	// 15 USD is 1800 JPY, the 356 cents of tax are 427.2 JPY rounded to 427
+	assert.Equal(s.T(), httpRec.Body.String(), `{"price":{"amount":1800,"currency":"JPY"},"taxes":[{"name":"Chicago parking tax","amount":{"amount":427,"currency":"JPY"}}],`+
+		`"total":{"amount":2227,"currency":"JPY"},"exchange":{"from":"USD","to":"JPY","rate":120,"as_of":"2015-07-01"}}`)
+}
+
+// TestGetPriceInvalidCurrency should respond 400 for the currency param which isn't an ISO-4217 code.
+func (s *Suite) TestGetPriceInvalidCurrency(){
+	s.expectFacility(1)
+	req, err := http.NewRequest("GET", "/price?start=2015-07-02T15:00:00-05:00&end=2015-07-02T20:00:00-05:00&currency=euro", nil)
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+
+	GetPrice(pricing.NewEngine(pricing.PolicyStrict), testExchange)(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusBadRequest)
+	assert.Equal(s.T(), httpRec.Body.String(), `{"code":"invalid_param","error":"Url param 'currency' is invalid, 'euro' isn't an ISO-4217 currency "}`)
+}
+
+// TestGetPriceNoExchangeRate should respond 422 when the provider has no exchange rate to the currency param.
+func (s *Suite) TestGetPriceNoExchangeRate(){
+	s.expectFacility(1)
+	s.expectTariff(testTariff{Rates: []model.Rate{s.rate}})
+
+	req, err := http.NewRequest("GET", "/price?start=2015-07-02T15:00:00-05:00&end=2015-07-02T20:00:00-05:00&currency=GBP", nil)
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+
+	GetPrice(pricing.NewEngine(pricing.PolicyStrict), testExchange)(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusUnprocessableEntity)
+	assert.Equal(s.T(), httpRec.Body.String(), `{"code":"unavailable","error":"no exchange rate from USD to GBP"}`)
 }
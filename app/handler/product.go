@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"io"
+	"net/http"
+	"spotHero/app/model"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// GetProducts api endpoints to get the products of the facility.
+func GetProducts(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
+	facility, ok := facilityID(db, w, r)
+	if !ok {
+		return
+	}
+
+	products := []model.Product{}
+	if err := db.Where("facility_id = ?", facility).Order("id").Find(&products).Error; err != nil {
+		respondError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, products)
+}
+
+// CreateProduct api endpoints to add the product to the facility.
+func CreateProduct(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
+	facility, ok := facilityID(db, w, r)
+	if !ok {
+		return
+	}
+
+	product, ok := decodeProduct(w, r)
+	if !ok {
+		return
+	}
+	product.FacilityID = facility
+
+	if err := db.Create(&product).Error; err != nil {
+		respondError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusCreated, product)
+}
+
+// GetProduct api endpoints to get the product of the facility by id.
+func GetProduct(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
+	product := getProductOr404(db, w, r)
+	if product == nil {
+		return
+	}
+	respondJSON(w, http.StatusOK, product)
+}
+
+// PutProduct api endpoints to replace the product of the facility by id.
+func PutProduct(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
+	stored := getProductOr404(db, w, r)
+	if stored == nil {
+		return
+	}
+
+	product, ok := decodeProduct(w, r)
+	if !ok {
+		return
+	}
+	product.ID, product.FacilityID = stored.ID, stored.FacilityID
+
+	if err := db.Save(&product).Error; err != nil {
+		respondError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, product)
+}
+
+// DeleteProduct api endpoints to delete the product of the facility by id.
+func DeleteProduct(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
+	product := getProductOr404(db, w, r)
+	if product == nil {
+		return
+	}
+
+	if err := db.Delete(product).Error; err != nil {
+		respondError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodeProduct decodes & validates the product of the request body, or respond the error otherwise
+func decodeProduct(w http.ResponseWriter, r *http.Request) (model.Product, bool) {
+	productInput := model.ProductInput{}
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&productInput); err != nil {
+		respondError(w, http.StatusBadRequest, ErrCodeInvalidBody, err.Error())
+		return model.Product{}, false
+	}
+
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+
+		}
+	}(r.Body)
+
+	product, validationErr := productInput.Validate()
+	if validationErr != nil {
+		respondValidationError(w, validationErr.(*model.ValidationError))
+		return model.Product{}, false
+	}
+	return product, true
+}
+
+// getProductOr404 gets the product of the facility & id path params if exists, or respond the 404 error otherwise
+func getProductOr404(db *gorm.DB, w http.ResponseWriter, r *http.Request) *model.Product {
+	facility, ok := facilityID(db, w, r)
+	if !ok {
+		return nil
+	}
+
+	param := mux.Vars(r)["id"]
+	id, parseErr := strconv.ParseUint(param, 10, 64)
+	if parseErr != nil {
+		respondError(w, http.StatusNotFound, ErrCodeNotFound, fmt.Sprintf("product '%s' not found", param))
+		return nil
+	}
+
+	product := model.Product{}
+	if err := db.Where("facility_id = ?", facility).First(&product, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(w, http.StatusNotFound, ErrCodeNotFound, fmt.Sprintf("product '%d' not found", id))
+		} else {
+			respondError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error())
+		}
+		return nil
+	}
+	return &product
+}
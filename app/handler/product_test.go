@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"spotHero/app/model"
+	"spotHero/app/pricing"
+)
+
+// productColumns are the columns of the products table.
+var productColumns = []string{"id", "facility_id", "name", "days", "entry_start", "entry_end", "exit_start", "exit_end", "tz", "price", "max_minutes", "vehicles"}
+
+// productRow returns the products table row of the product.
+func productRow(product model.Product) []driver.Value {
+	return []driver.Value{product.ID, product.FacilityID, product.Name, product.Days, product.EntryStart, product.EntryEnd,
+		product.ExitStart, product.ExitEnd, product.Tz, product.Price, product.MaxMinutes, product.Vehicles}
+}
+
+// earlyBird returns the weekday early-bird product of the default facility: enter by 9:00, leave after 15:00.
+func earlyBird(price int) model.Product {
+	productPrice := price
+	product, _ := model.ProductInput{Name: "Early bird", Days: "mon,tues,wed,thurs,fri", Entry: "0500-0900", Exit: "1500-2000", Tz: "America/Chicago", Price: &productPrice}.Validate()
+	product.ID, product.FacilityID = 1, model.DefaultFacilityID
+	return product
+}
+
+// TestGetPriceProduct return the product price when it's cheaper than the rates, with the product & the rate segments it beats in the breakdown.
+func (s *Suite) TestGetPriceProduct(){
+	s.expectFacility(1)
+	s.expectTariff(testTariff{Rates: []model.Rate{rate(s, "thurs", "0000-2400", "America/Chicago", 2500)}, Products: []model.Product{earlyBird(1200)}})
+
+	req, err := http.NewRequest("GET", "/price?start=2015-07-02T08:30:00-05:00&end=2015-07-02T17:00:00-05:00&detail=true", nil)
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+
+	GetPrice(pricing.NewEngine(pricing.PolicyStrict), testExchange)(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusOK)
+	assert.Equal(s.T(), httpRec.Body.String(), `{"price":{"amount":1200,"currency":"USD"},"total":{"amount":1200,"currency":"USD"},"breakdown":{"currency":"USD","product":{"id":1,"name":"Early bird","days":"mon,tues,wed,thurs,fri",`+
+		`"entry":"0500-0900","exit":"1500-2000","tz":"America/Chicago","price":1200},"segments":[{"days":"thurs","times":"0000-2400","tz":"America/Chicago","kind":"flat",`+
+		`"start":"2015-07-02T08:30:00-05:00","end":"2015-07-02T17:00:00-05:00","minutes":510,"amount":2500}],"subtotal":2500,"adjustments":[]}}`)
+}
+
+// TestGetPriceProductVehicle return the product price multiplied for the vehicle param.
+func (s *Suite) TestGetPriceProductVehicle(){
+	product := earlyBird(1200)
+	product.Vehicles = model.VehicleMultipliers{model.VehicleOversize: 1.5}
+	s.expectFacility(1)
+	s.expectTariff(testTariff{Rates: []model.Rate{rate(s, "thurs", "0000-2400", "America/Chicago", 2500)}, Products: []model.Product{product}})
+
+	req, err := http.NewRequest("GET", "/price?start=2015-07-02T08:30:00-05:00&end=2015-07-02T17:00:00-05:00&vehicle=oversize", nil)
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+
+	GetPrice(pricing.NewEngine(pricing.PolicyStrict), testExchange)(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusOK)
+	assert.Equal(s.T(), httpRec.Body.String(), `{"price":{"amount":1800,"currency":"USD"},"total":{"amount":1800,"currency":"USD"}}`)
+}
+
+// TestGetPriceProductMissedEntry return the rate price when the stay is entered after the product entry window.
+func (s *Suite) TestGetPriceProductMissedEntry(){
+	s.expectFacility(1)
+	s.expectTariff(testTariff{Rates: []model.Rate{rate(s, "thurs", "0000-2400", "America/Chicago", 2500)}, Products: []model.Product{earlyBird(1200)}})
+
+	req, err := http.NewRequest("GET", "/price?start=2015-07-02T09:30:00-05:00&end=2015-07-02T17:00:00-05:00", nil)
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+
+	GetPrice(pricing.NewEngine(pricing.PolicyStrict), testExchange)(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusOK)
+
+	price := Price{}
+	assert.NoError(s.T(), json.Unmarshal(httpRec.Body.Bytes(), &price))
+	assert.Equal(s.T(), price.Price, usd(2500))
+}
+
+// TestCreateProduct test the product is added to the facility.
+func (s *Suite) TestCreateProduct(){
+	s.expectFacility(1)
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec("INSERT INTO `products`(.*)").
+		WithArgs(1, "Early bird", model.Weekdays(62), model.TimeOfDay(300), model.TimeOfDay(540), model.TimeOfDay(900), model.TimeOfDay(1200), "America/Chicago", 1200, 600, `{"oversize":1.5}`).
+		WillReturnResult(sqlmock.NewResult(3, 1))
+	s.mock.ExpectCommit()
+
+	req, err := http.NewRequest("POST", "/products", bytes.NewBufferString(`{"name":"Early bird","days":"mon,tues,wed,thurs,fri","entry":"0500-0900",`+
+		`"exit":"1500-2000","tz":"America/Chicago","price":1200,"max_minutes":600,"vehicles":{"oversize":1.5}}`))
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+	CreateProduct(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusCreated)
+	assert.Equal(s.T(), httpRec.Body.String(), `{"id":3,"name":"Early bird","days":"mon,tues,wed,thurs,fri","entry":"0500-0900",`+
+		`"exit":"1500-2000","tz":"America/Chicago","price":1200,"max_minutes":600,"vehicles":{"oversize":1.5}}`)
+}
+
+// TestCreateProductInvalid should respond 422 with every invalid field of the product.
+func (s *Suite) TestCreateProductInvalid(){
+	s.expectFacility(1)
+	req, err := http.NewRequest("POST", "/products", bytes.NewBufferString(`{"name":"Event","days":"sat","entry":"1800-2000","exit":"1200-1500","tz":"America/Chicago","max_minutes":-1,"vehicles":{"bus":2}}`))
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+	CreateProduct(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusUnprocessableEntity)
+	assert.Equal(s.T(), httpRec.Body.String(), `{"code":"validation_failed","error":"validation failed","fields":[{"field":"exit","message":"'1200-1500' ends before entry '1800-2000' starts"},`+
+		`{"field":"price","message":"is required"},{"field":"max_minutes","message":"can't be negative"},`+
+		`{"field":"vehicles.bus","message":"unknown vehicle 'bus', isn't compact, standard, oversize or motorcycle"}]}`)
+}
+
+// TestDeleteProductNotFound should respond 404 for the unknown product id.
+func (s *Suite) TestDeleteProductNotFound(){
+	s.expectFacility(1)
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `products` WHERE facility_id = ? AND `products`.`id` = ?")).
+		WithArgs(1, 9).
+		WillReturnRows(s.mock.NewRows(productColumns))
+
+	req, err := http.NewRequest("DELETE", "/products/9", nil)
+	assert.NoError(s.T(), err)
+	req = mux.SetURLVars(req, map[string]string{"id": "9"})
+	httpRec := httptest.NewRecorder()
+	DeleteProduct(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusNotFound)
+	assert.Equal(s.T(), httpRec.Body.String(), `{"code":"not_found","error":"product '9' not found"}`)
+}
@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"io"
+	"net/http"
+	"spotHero/app/model"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// GetPromos api endpoints to get the promo codes stored in the database.
+func GetPromos(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
+	promos := []model.PromoCode{}
+	if err := db.Order("id").Find(&promos).Error; err != nil {
+		respondError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, promos)
+}
+
+// CreatePromo api endpoints to add the promo code, the code can't be taken by another promo code.
+func CreatePromo(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
+	promo, ok := decodePromo(w, r)
+	if !ok {
+		return
+	}
+	savePromo(db, w, &promo, http.StatusCreated)
+}
+
+// GetPromo api endpoints to get the promo code by id.
+func GetPromo(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
+	promo := getPromoOr404(db, w, r)
+	if promo == nil {
+		return
+	}
+	respondJSON(w, http.StatusOK, promo)
+}
+
+// PutPromo api endpoints to replace the promo code by id, its uses are kept.
+func PutPromo(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
+	stored := getPromoOr404(db, w, r)
+	if stored == nil {
+		return
+	}
+
+	promo, ok := decodePromo(w, r)
+	if !ok {
+		return
+	}
+	promo.ID, promo.Uses = stored.ID, stored.Uses
+	savePromo(db, w, &promo, http.StatusOK)
+}
+
+// DeletePromo api endpoints to delete the promo code by id, the reservations keep the code they were booked with.
+func DeletePromo(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
+	promo := getPromoOr404(db, w, r)
+	if promo == nil {
+		return
+	}
+
+	if err := db.Delete(promo).Error; err != nil {
+		respondError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodePromo decodes & validates the promo code of the request body, or respond the error otherwise
+func decodePromo(w http.ResponseWriter, r *http.Request) (model.PromoCode, bool) {
+	promoInput := model.PromoCodeInput{}
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&promoInput); err != nil {
+		respondError(w, http.StatusBadRequest, ErrCodeInvalidBody, err.Error())
+		return model.PromoCode{}, false
+	}
+
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+
+		}
+	}(r.Body)
+
+	promo, validationErr := promoInput.Validate()
+	if validationErr != nil {
+		respondValidationError(w, validationErr.(*model.ValidationError))
+		return model.PromoCode{}, false
+	}
+	return promo, true
+}
+
+// errPromoCodeTaken is returned on saving the promo code having the code of another promo code.
+var errPromoCodeTaken = errors.New("promo code is taken")
+
+// savePromo saves the promo code unless another promo code has the code, and respond it with the status.
+func savePromo(db *gorm.DB, w http.ResponseWriter, promo *model.PromoCode, status int) {
+	saveErr := db.Transaction(func(tx *gorm.DB) error {
+		var taken int64
+		if err := tx.Model(&model.PromoCode{}).Where("code = ? AND id <> ?", promo.Code, promo.ID).Count(&taken).Error; err != nil {
+			return err
+		}
+		if taken > 0 {
+			return errPromoCodeTaken
+		}
+		return tx.Save(promo).Error
+	})
+
+	if errors.Is(saveErr, errPromoCodeTaken) {
+		respondError(w, http.StatusConflict, ErrCodeConflict, fmt.Sprintf("promo code '%s' already exists", promo.Code))
+		return
+	}
+
+	if saveErr != nil {
+		respondError(w, http.StatusInternalServerError, ErrCodeInternal, saveErr.Error())
+		return
+	}
+	respondJSON(w, status, promo)
+}
+
+// getPromoOr404 gets the promo code of the id path param if exists, or respond the 404 error otherwise
+func getPromoOr404(db *gorm.DB, w http.ResponseWriter, r *http.Request) *model.PromoCode {
+	param := mux.Vars(r)["id"]
+	id, parseErr := strconv.ParseUint(param, 10, 64)
+	if parseErr != nil {
+		respondError(w, http.StatusNotFound, ErrCodeNotFound, fmt.Sprintf("promo code '%s' not found", param))
+		return nil
+	}
+
+	promo := model.PromoCode{}
+	if err := db.First(&promo, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(w, http.StatusNotFound, ErrCodeNotFound, fmt.Sprintf("promo code '%d' not found", id))
+		} else {
+			respondError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error())
+		}
+		return nil
+	}
+	return &promo
+}
+
+// findPromo returns the promo code of the code if it can be applied at the facility at the instant,
+// or the PromoError with the reason otherwise.
+func findPromo(db *gorm.DB, code string, facility uint, at time.Time) (*model.PromoCode, error) {
+	code = model.NormalizePromoCode(code)
+
+	var promos []model.PromoCode
+	if err := db.Where("code = ?", code).Limit(1).Find(&promos).Error; err != nil {
+		return nil, err
+	}
+	if len(promos) == 0 {
+		return nil, &model.PromoError{Code: code, Reason: "doesn't exist"}
+	}
+	if err := promos[0].Check(facility, at); err != nil {
+		return nil, err
+	}
+	return &promos[0], nil
+}
+
+// respondPromoError makes the unprocessable entity response with the reason the promo code can't be applied,
+// or the internal error response otherwise
+func respondPromoError(w http.ResponseWriter, err error) {
+	var promoErr *model.PromoError
+	if errors.As(err, &promoErr) {
+		respondError(w, http.StatusUnprocessableEntity, ErrCodeInvalidPromo, promoErr.Error())
+		return
+	}
+	respondError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error())
+}
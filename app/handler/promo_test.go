@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"spotHero/app/model"
+	"spotHero/app/pricing"
+	"time"
+)
+
+// promoColumns are the columns of the promo_codes table.
+var promoColumns = []string{"id", "code", "kind", "percent", "amount", "currency", "valid_from", "valid_to", "max_uses", "uses", "facilities"}
+
+// expectPromo expects the lookup of the promo code by code.
+func (s *Suite) expectPromo(code string, rows *sqlmock.Rows) {
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `promo_codes` WHERE code = ? LIMIT 1")).
+		WithArgs(code).
+		WillReturnRows(rows)
+}
+
+// TestGetPricePromo return the price discounted by the promo code, the code is matched regardless of case.
+func (s *Suite) TestGetPricePromo(){
+	s.expectFacility(1)
+	s.expectPromo("TENOFF", s.mock.NewRows(promoColumns).AddRow(1, "TENOFF", "percent_off", 10, 0, "", nil, nil, 0, 3, nil))
+	s.expectTariff(testTariff{Rates: []model.Rate{s.rate}})
+
+	req, err := http.NewRequest("GET", "/price?start=2015-07-02T15:00:00-05:00&end=2015-07-02T20:00:00-05:00&promo=tenOff&detail=true", nil)
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+
+	GetPrice(pricing.NewEngine(pricing.PolicyStrict), testExchange)(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusOK)
+
+	var price Price
+	assert.NoError(s.T(), json.Unmarshal(httpRec.Body.Bytes(), &price))
+	assert.Equal(s.T(), price.Price, usd(1350))
+	assert.Equal(s.T(), price.Breakdown.Adjustments, []PriceAdjustment{{Name: "promo TENOFF", Amount: -150}})
+}
+
+// TestGetPriceExpiredPromo should respond 422 with the reason the promo code can't be applied.
+func (s *Suite) TestGetPriceExpiredPromo(){
+	s.expectFacility(1)
+	validTo := time.Date(2015, time.August, 1, 0, 0, 0, 0, time.UTC)
+	s.expectPromo("SUMMER", s.mock.NewRows(promoColumns).AddRow(1, "SUMMER", "fixed_off", 0, 500, "USD", nil, validTo, 0, 0, nil))
+
+	req, err := http.NewRequest("GET", "/price?start=2015-07-02T15:00:00-05:00&end=2015-07-02T20:00:00-05:00&promo=summer", nil)
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+
+	GetPrice(pricing.NewEngine(pricing.PolicyStrict), testExchange)(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusUnprocessableEntity)
+	assert.Equal(s.T(), httpRec.Body.String(), `{"code":"invalid_promo","error":"promo code 'SUMMER' expired at 2015-08-01T00:00:00Z"}`)
+}
+
+// TestGetPricePromoOtherCurrency should respond 422 for the fixed off promo code which isn't in the facility currency.
+func (s *Suite) TestGetPricePromoOtherCurrency(){
+	s.expectFacility(1)
+	s.expectPromo("SUMMER", s.mock.NewRows(promoColumns).AddRow(1, "SUMMER", "fixed_off", 0, 500, "USD", nil, nil, 0, 0, nil))
+	s.expectTariff(testTariff{Rates: []model.Rate{s.rate}, Currency: "EUR"})
+
+	req, err := http.NewRequest("GET", "/price?start=2015-07-02T15:00:00-05:00&end=2015-07-02T20:00:00-05:00&promo=summer", nil)
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+
+	GetPrice(pricing.NewEngine(pricing.PolicyStrict), testExchange)(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusUnprocessableEntity)
+	assert.Equal(s.T(), httpRec.Body.String(), `{"code":"invalid_promo","error":"promo code 'SUMMER' is in USD, the facility prices are in EUR"}`)
+}
+
+// TestGetPriceUnknownPromo should respond 422 for the promo code which doesn't exist.
+func (s *Suite) TestGetPriceUnknownPromo(){
+	s.expectFacility(1)
+	s.expectPromo("NOPE", s.mock.NewRows(promoColumns))
+
+	req, err := http.NewRequest("GET", "/price?start=2015-07-02T15:00:00-05:00&end=2015-07-02T20:00:00-05:00&promo=nope", nil)
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+
+	GetPrice(pricing.NewEngine(pricing.PolicyStrict), testExchange)(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusUnprocessableEntity)
+	assert.Equal(s.T(), httpRec.Body.String(), `{"code":"invalid_promo","error":"promo code 'NOPE' doesn't exist"}`)
+}
+
+// TestCreatePromo test the promo code is stored upper case.
+func (s *Suite) TestCreatePromo(){
+	s.mock.ExpectBegin()
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM `promo_codes` WHERE code = ? AND id <> ?")).
+		WithArgs("SUMMER", 0).
+		WillReturnRows(s.mock.NewRows([]string{"count"}).AddRow(0))
+	s.mock.ExpectExec("INSERT INTO `promo_codes`(.*)").
+		WithArgs("SUMMER", model.PromoFixedOff, 0, 500, model.Currency("USD"), nil, nil, 0, 0, nil).
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	s.mock.ExpectCommit()
+
+	req, err := http.NewRequest("POST", "/promos", bytes.NewBufferString(`{"code":"summer","kind":"fixed_off","amount":500}`))
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+	CreatePromo(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusCreated)
+	assert.Equal(s.T(), httpRec.Body.String(), `{"id":2,"code":"SUMMER","kind":"fixed_off","amount":500,"currency":"USD","max_uses":0,"uses":0}`)
+}
+
+// TestCreatePromoConflict should respond 409 for the code taken by another promo code.
+func (s *Suite) TestCreatePromoConflict(){
+	s.mock.ExpectBegin()
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM `promo_codes` WHERE code = ? AND id <> ?")).
+		WithArgs("SUMMER", 0).
+		WillReturnRows(s.mock.NewRows([]string{"count"}).AddRow(1))
+	s.mock.ExpectRollback()
+
+	req, err := http.NewRequest("POST", "/promos", bytes.NewBufferString(`{"code":"Summer","kind":"free_first_hour"}`))
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+	CreatePromo(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusConflict)
+	assert.Equal(s.T(), httpRec.Body.String(), `{"code":"conflict","error":"promo code 'SUMMER' already exists"}`)
+}
+
+// TestCreatePromoInvalid should respond 422 with every invalid field of the promo code.
+func (s *Suite) TestCreatePromoInvalid(){
+	req, err := http.NewRequest("POST", "/promos", bytes.NewBufferString(`{"kind":"half_off","amount":500}`))
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+	CreatePromo(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusUnprocessableEntity)
+	assert.Equal(s.T(), httpRec.Body.String(), `{"code":"validation_failed","error":"validation failed","fields":[{"field":"code","message":"is required"},`+
+		`{"field":"kind","message":"unknown kind 'half_off', isn't percent_off, fixed_off or free_first_hour"},{"field":"amount","message":"is only for the fixed_off code"}]}`)
+}
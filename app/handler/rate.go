@@ -2,33 +2,171 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"gorm.io/gorm"
-	"gorm.io/gorm/clause"
 	"io"
 	"net/http"
 	"spotHero/app/model"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
 )
 
-// GetAllRates api endpoints to get all the rates stored in the database.
+// GetAllRates api endpoints to get the rates of the facility stored in the database, filtered, sorted & paginated as per the query params.
+// The total count of the filtered rates is in the X-Total-Count header and the next page url in the Link header.
 func GetAllRates(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
-	var rates []model.Rate
-	getError := db.Find(&rates).Error
+	facility, ok := facilityID(db, w, r)
+	if !ok {
+		return
+	}
+
+	rateQuery, queryErr := parseRateQuery(r.URL.Query())
+	if queryErr != nil {
+		respondError(w, http.StatusBadRequest, ErrCodeInvalidParam, queryErr.Error())
+		return
+	}
+	rateQuery.where("facility_id = ?", facility)
+
+	var total int64
+	if countErr := rateQuery.filter(db).Count(&total).Error; countErr != nil {
+		respondError(w, http.StatusInternalServerError, ErrCodeInternal, countErr.Error())
+		return
+	}
+
+	rates := []model.Rate{}
+	getError := rateQuery.page(db).Find(&rates).Error
 	if getError != nil {
-		respondError(w, http.StatusBadRequest, getError.Error())
+		respondError(w, http.StatusInternalServerError, ErrCodeInternal, getError.Error())
+		return
 	}
-	respondJSON(w, http.StatusOK, rates)
+
+	w.Header().Set("X-Total-Count", strconv.FormatInt(total, 10))
+	if next := rateQuery.nextLink(r.URL, total); next != "" {
+		w.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"next\"", next))
+	}
+	respondRates(db, w, http.StatusOK, facility, func(currency model.Currency) interface{} {
+		return model.NewRatesOutput(rates, currency).Rates
+	})
 }
 
-// PutRate api endpoints to upsert the rate in the database
-func PutRate(db *gorm.DB, w http.ResponseWriter, r *http.Request){
-	rate := model.Rate{}
+// PutRates api endpoints to replace all the rates of the facility in the database with the rate list, within a single transaction.
+func PutRates(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
+	auditor := newAuditor(w, r)
+	facility, ok := facilityID(db, w, r)
+	if !ok {
+		return
+	}
+	ratesInput := model.RatesInput{}
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&ratesInput); err != nil {
+		respondError(w, http.StatusBadRequest, ErrCodeInvalidBody, err.Error())
+		return
+	}
+
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+
+		}
+	}(r.Body)
+
+	rates, validationErr := ratesInput.Validate()
+	if validationErr != nil {
+		respondValidationError(w, validationErr.(*model.ValidationError))
+		return
+	}
+
+	// rejecting the rate list having overlapping rates with the same priority
+	if overlapErr := model.CheckOverlaps(rates); overlapErr != nil {
+		respondError(w, http.StatusConflict, ErrCodeConflict, overlapErr.Error())
+		return
+	}
+
+	replaceErr := db.Transaction(func(tx *gorm.DB) error {
+		// keeping the id of the stored rates having the same days, times & tz
+		var storedRates []model.Rate
+		if err := tx.Where("facility_id = ?", facility).Find(&storedRates).Error; err != nil {
+			return err
+		}
+		replaced := map[uint]*model.Rate{}
+		for i := range rates {
+			rates[i].FacilityID = facility
+			if stored := findSameKey(rates[i], storedRates); stored != nil {
+				rates[i].ID = stored.ID
+				replaced[stored.ID] = stored
+			}
+		}
+
+		if err := tx.Where("facility_id = ?", facility).Delete(&model.Rate{}).Error; err != nil {
+			return err
+		}
+		for i := range rates {
+			if err := tx.Create(&rates[i]).Error; err != nil {
+				return err
+			}
+		}
+
+		// recording the deleted, created & changed rates, the unchanged rates aren't recorded
+		for i := range storedRates {
+			if replaced[storedRates[i].ID] == nil {
+				if err := auditor.Record(tx, model.AuditDelete, &storedRates[i], nil); err != nil {
+					return err
+				}
+			}
+		}
+		for i := range rates {
+			stored := replaced[rates[i].ID]
+			if stored == nil {
+				if err := auditor.Record(tx, model.AuditCreate, nil, &rates[i]); err != nil {
+					return err
+				}
+			} else if !sameRate(*stored, rates[i]) {
+				if err := auditor.Record(tx, model.AuditUpdate, stored, &rates[i]); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+
+	if replaceErr != nil {
+		respondError(w, http.StatusInternalServerError, ErrCodeInternal, replaceErr.Error())
+		return
+	}
+
+	respondRates(db, w, http.StatusOK, facility, func(currency model.Currency) interface{} {
+		return model.NewRatesOutput(rates, currency)
+	})
+}
+
+// UpsertRate api endpoints to upsert the rate of the facility in the database
+func UpsertRate(db *gorm.DB, w http.ResponseWriter, r *http.Request){
+	auditor := newAuditor(w, r)
+	facility, ok := facilityID(db, w, r)
+	if !ok {
+		return
+	}
+	rateInput := model.RateInput{}
 
 	decoder := json.NewDecoder(r.Body)
-	if err := decoder.Decode(&rate); err != nil {
-		respondError(w, http.StatusBadRequest, err.Error())
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&rateInput); err != nil {
+		respondError(w, http.StatusBadRequest, ErrCodeInvalidBody, err.Error())
 		return
 	}
 
+	rate, validationErr := rateInput.Validate()
+	if validationErr != nil {
+		respondValidationError(w, validationErr.(*model.ValidationError))
+		return
+	}
+	rate.FacilityID = facility
+
 	defer func(Body io.ReadCloser) {
 		err := Body.Close()
 		if err != nil {
@@ -36,15 +174,258 @@ func PutRate(db *gorm.DB, w http.ResponseWriter, r *http.Request){
 		}
 	}(r.Body)
 
-	upsertErr := db.Clauses(clause.OnConflict{
-		Columns:   []clause.Column{{Name: "days"}, {Name: "times"}, {Name: "tz"}}, // key colume
-		DoUpdates: clause.AssignmentColumns([]string{"price"}), // column needed to be updated
-	}).Create(&rate).Error
+	upsertErr := db.Transaction(func(tx *gorm.DB) error {
+		// rejecting the rate overlapping any stored rate of the facility & tz with the same priority
+		var tzRates []model.Rate
+		if err := tx.Where("facility_id = ? AND tz = ?", facility, rate.Tz).Find(&tzRates).Error; err != nil {
+			return err
+		}
+		if err := model.CheckOverlap(rate, tzRates); err != nil {
+			return err
+		}
+
+		// updating the stored rate having the same days, times, tz & effective from, otherwise creating it
+		if stored := findSameKey(rate, tzRates); stored != nil {
+			rate.ID = stored.ID
+			if err := tx.Save(&rate).Error; err != nil {
+				return err
+			}
+			return auditor.Record(tx, model.AuditUpdate, stored, &rate)
+		}
+		if err := tx.Create(&rate).Error; err != nil {
+			return err
+		}
+		return auditor.Record(tx, model.AuditCreate, nil, &rate)
+	})
+
+	var overlapErr *model.OverlapError
+	if errors.As(upsertErr, &overlapErr) {
+		respondError(w, http.StatusConflict, ErrCodeConflict, overlapErr.Error())
+		return
+	}
 
 	if upsertErr != nil {
-		respondError(w, http.StatusInternalServerError, upsertErr.Error())
+		respondError(w, http.StatusInternalServerError, ErrCodeInternal, upsertErr.Error())
+		return
+	}
+
+	respondRates(db, w, http.StatusCreated, facility, func(currency model.Currency) interface{} {
+		return rate.Output(currency)
+	})
+}
+
+// RateChange is the scheduled change of the rates: the rate taking effect, replacing the version in force before, or expiring.
+type RateChange struct {
+	At       time.Time        `json:"at"`
+	Change   string           `json:"change"`
+	Rate     model.RateOutput `json:"rate"`
+	Replaces *uint            `json:"replaces,omitempty"`
+}
+
+// GetScheduledRates api endpoints to list the facility rate changes scheduled after the from query param (now by default), ordered by time.
+func GetScheduledRates(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
+	facility, ok := facilityID(db, w, r)
+	if !ok {
+		return
+	}
+
+	from := time.Now()
+	if r.URL.Query().Get("from") != "" {
+		fromTime, fromErr := validateTimeParam(r.URL, "from")
+		if fromErr != nil {
+			respondError(w, http.StatusBadRequest, ErrCodeInvalidParam, fromErr.Error())
+			return
+		}
+		from = *fromTime
+	}
+
+	var rates []model.Rate
+	if err := db.Where("facility_id = ?", facility).Find(&rates).Error; err != nil {
+		respondError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error())
+		return
+	}
+
+	currency, currencyErr := facilityCurrency(db, facility)
+	if currencyErr != nil {
+		respondError(w, http.StatusInternalServerError, ErrCodeInternal, currencyErr.Error())
+		return
+	}
+
+	changes := []RateChange{}
+	for _, rate := range rates {
+		if rate.EffectiveFrom != nil && rate.EffectiveFrom.After(from) {
+			change := RateChange{At: *rate.EffectiveFrom, Change: "effective", Rate: rate.Output(currency)}
+			if replaced := versionBefore(rate, rates); replaced != nil {
+				change.Replaces = &replaced.ID
+			}
+			changes = append(changes, change)
+		}
+		if rate.EffectiveTo != nil && rate.EffectiveTo.After(from) {
+			changes = append(changes, RateChange{At: *rate.EffectiveTo, Change: "expires", Rate: rate.Output(currency)})
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].At.Before(changes[j].At)
+	})
+	respondJSON(w, http.StatusOK, changes)
+}
+
+// versionBefore returns the version of the rate window in force just before the rate takes effect, or nil.
+func versionBefore(rate model.Rate, rates []model.Rate) *model.Rate {
+	before := rate.EffectiveFrom.Add(-time.Nanosecond)
+	var found *model.Rate
+	for i, other := range rates {
+		if !other.SameWindow(rate) || other.ID == rate.ID || !other.InForce(before) {
+			continue
+		}
+		if found == nil || found.EffectiveFrom == nil || other.EffectiveFrom != nil && other.EffectiveFrom.After(*found.EffectiveFrom) {
+			found = &rates[i]
+		}
+	}
+	return found
+}
+
+// GetRate api endpoints to get the rate by id.
+func GetRate(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
+	rate := getRateOr404(db, w, r)
+	if rate == nil {
+		return
+	}
+	respondRates(db, w, http.StatusOK, rate.FacilityID, func(currency model.Currency) interface{} {
+		return rate.Output(currency)
+	})
+}
+
+// PatchRate api endpoints to change only the supplied fields of the rate.
+func PatchRate(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
+	auditor := newAuditor(w, r)
+	rate := getRateOr404(db, w, r)
+	if rate == nil {
+		return
+	}
+
+	ratePatch := model.RatePatch{}
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&ratePatch); err != nil {
+		respondError(w, http.StatusBadRequest, ErrCodeInvalidBody, err.Error())
+		return
+	}
+
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+
+		}
+	}(r.Body)
+
+	patched, validationErr := ratePatch.Apply(*rate)
+	if validationErr != nil {
+		respondValidationError(w, validationErr.(*model.ValidationError))
 		return
 	}
 
-	respondJSON(w, http.StatusCreated, rate)
+	patchErr := db.Transaction(func(tx *gorm.DB) error {
+		// rejecting the patched rate overlapping any other stored rate of the facility & tz with the same priority
+		var tzRates []model.Rate
+		if err := tx.Where("facility_id = ? AND tz = ?", patched.FacilityID, patched.Tz).Find(&tzRates).Error; err != nil {
+			return err
+		}
+		if err := model.CheckOverlap(patched, tzRates); err != nil {
+			return err
+		}
+		if err := tx.Save(&patched).Error; err != nil {
+			return err
+		}
+		return auditor.Record(tx, model.AuditUpdate, rate, &patched)
+	})
+
+	var overlapErr *model.OverlapError
+	if errors.As(patchErr, &overlapErr) {
+		respondError(w, http.StatusConflict, ErrCodeConflict, overlapErr.Error())
+		return
+	}
+
+	if patchErr != nil {
+		respondError(w, http.StatusInternalServerError, ErrCodeInternal, patchErr.Error())
+		return
+	}
+
+	respondRates(db, w, http.StatusOK, patched.FacilityID, func(currency model.Currency) interface{} {
+		return patched.Output(currency)
+	})
+}
+
+// DeleteRate api endpoints to delete the rate by id.
+func DeleteRate(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
+	auditor := newAuditor(w, r)
+	rate := getRateOr404(db, w, r)
+	if rate == nil {
+		return
+	}
+
+	deleteErr := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(rate).Error; err != nil {
+			return err
+		}
+		return auditor.Record(tx, model.AuditDelete, rate, nil)
+	})
+	if deleteErr != nil {
+		respondError(w, http.StatusInternalServerError, ErrCodeInternal, deleteErr.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getRateOr404 gets the rate of the facility & id path params if exists, or respond the 404 error otherwise
+func getRateOr404(db *gorm.DB, w http.ResponseWriter, r *http.Request) *model.Rate {
+	facility, ok := facilityID(db, w, r)
+	if !ok {
+		return nil
+	}
+
+	id, parseErr := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if parseErr != nil {
+		respondError(w, http.StatusNotFound, ErrCodeNotFound, fmt.Sprintf("rate '%s' not found", mux.Vars(r)["id"]))
+		return nil
+	}
+
+	rate := model.Rate{}
+	if err := db.Where("facility_id = ?", facility).First(&rate, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(w, http.StatusNotFound, ErrCodeNotFound, fmt.Sprintf("rate '%d' not found", id))
+		} else {
+			respondError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error())
+		}
+		return nil
+	}
+	return &rate
+}
+
+// sameRate check if both rates have the same wire format, i.e. storing one over the other isn't a change.
+func sameRate(rate, other model.Rate) bool {
+	data, err := json.Marshal(rate)
+	otherData, otherErr := json.Marshal(other)
+	return err == nil && otherErr == nil && string(data) == string(otherData)
+}
+
+// findSameKey returns the rate having the same days, times & tz as the given rate, or nil.
+func findSameKey(rate model.Rate, rates []model.Rate) *model.Rate {
+	for i := range rates {
+		if rates[i].SameKey(rate) {
+			return &rates[i]
+		}
+	}
+	return nil
+}
+
+// respondRates makes the response with the rates payload of the facility, the payload has the rate prices in the facility currency.
+func respondRates(db *gorm.DB, w http.ResponseWriter, status int, facility uint, payload func(currency model.Currency) interface{}) {
+	currency, err := facilityCurrency(db, facility)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error())
+		return
+	}
+	respondJSON(w, status, payload(currency))
 }
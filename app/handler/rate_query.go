@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"fmt"
+	"net/url"
+	"spotHero/app/model"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultRatesLimit is the page size of GET /rates when no limit is given
+	defaultRatesLimit = 100
+	// maxRatesLimit is the largest page size of GET /rates
+	maxRatesLimit = 1000
+)
+
+// rateSortColumns maps the sort param values to the rates table columns.
+var rateSortColumns = map[string]string{
+	"id":       "id",
+	"days":     "days",
+	"start":    "start_time",
+	"end":      "end_time",
+	"tz":       "tz",
+	"price":    "price",
+	"priority": "priority",
+}
+
+// rateQuery contains the filter, sort & pagination params of GET /rates.
+type rateQuery struct {
+	scopes []func(db *gorm.DB) *gorm.DB
+	order  string
+	limit  int
+	offset int
+}
+
+// parseRateQuery parse the GET /rates query params:
+// day, tz, min_price, max_price, covers (HHMM or HHMM-HHMM), sort, order (asc or desc), limit & offset.
+func parseRateQuery(query url.Values) (*rateQuery, error) {
+	rateQuery := &rateQuery{order: "id", limit: defaultRatesLimit}
+
+	if day := query.Get("day"); day != "" {
+		weekdays, err := model.ParseWeekdays(day)
+		if err != nil {
+			return nil, err
+		}
+		rateQuery.where("days & ? != 0", int(weekdays))
+	}
+
+	if tz := query.Get("tz"); tz != "" {
+		rateQuery.where("tz = ?", tz)
+	}
+
+	for _, priceParam := range []struct{ param, condition string }{{"min_price", "price >= ?"}, {"max_price", "price <= ?"}} {
+		param, condition := priceParam.param, priceParam.condition
+		if value := query.Get(param); value != "" {
+			price, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("Url param '%s' isn't a number ", param)
+			}
+			rateQuery.where(condition, price)
+		}
+	}
+
+	if covers := query.Get("covers"); covers != "" {
+		if !strings.Contains(covers, "-") {
+			// a time of day is covered by the window [start, end)
+			timeOfDay, err := model.ParseTimeOfDay(covers)
+			if err != nil {
+				return nil, err
+			}
+			rateQuery.where("start_time <= ? AND end_time > ?", int(timeOfDay), int(timeOfDay))
+		} else {
+			window, err := model.ParseTimeWindow(covers)
+			if err != nil {
+				return nil, err
+			}
+			rateQuery.where("start_time <= ? AND end_time >= ?", int(window.Start), int(window.End))
+		}
+	}
+
+	if sort := query.Get("sort"); sort != "" {
+		column, isKnown := rateSortColumns[sort]
+		if !isKnown {
+			return nil, fmt.Errorf("Url param 'sort' has unknown value '%s' ", sort)
+		}
+		rateQuery.order = column
+	}
+
+	switch order := query.Get("order"); order {
+	case "", "asc":
+	case "desc":
+		rateQuery.order += " DESC"
+	default:
+		return nil, fmt.Errorf("Url param 'order' has unknown value '%s' ", order)
+	}
+	if rateQuery.order != "id" && rateQuery.order != "id DESC" {
+		// keeping the order stable across pages for equal values
+		rateQuery.order += ", id"
+	}
+
+	var err error
+	if rateQuery.limit, err = intParam(query, "limit", defaultRatesLimit, 1, maxRatesLimit); err != nil {
+		return nil, err
+	}
+	if rateQuery.offset, err = intParam(query, "offset", 0, 0, -1); err != nil {
+		return nil, err
+	}
+
+	return rateQuery, nil
+}
+
+// where adds the filter condition to the query.
+func (q *rateQuery) where(condition string, args ...interface{}) {
+	q.scopes = append(q.scopes, func(db *gorm.DB) *gorm.DB {
+		return db.Where(condition, args...)
+	})
+}
+
+// filter returns the db scoped with the filter conditions.
+func (q *rateQuery) filter(db *gorm.DB) *gorm.DB {
+	return db.Model(&model.Rate{}).Scopes(q.scopes...)
+}
+
+// page returns the db scoped with the filter conditions, sort & pagination.
+func (q *rateQuery) page(db *gorm.DB) *gorm.DB {
+	return q.filter(db).Order(q.order).Limit(q.limit).Offset(q.offset)
+}
+
+// nextLink returns the url of the next page, or empty string if this is the last page.
+func (q *rateQuery) nextLink(url *url.URL, total int64) string {
+	if int64(q.offset+q.limit) >= total {
+		return ""
+	}
+	query := url.Query()
+	query.Set("limit", strconv.Itoa(q.limit))
+	query.Set("offset", strconv.Itoa(q.offset+q.limit))
+	return url.Path + "?" + query.Encode()
+}
+
+// intParam parse the int query param, with the default value if missing & the bounds (max < 0 is unbounded).
+func intParam(query url.Values, paramName string, defaultValue, min, max int) (int, error) {
+	param := query.Get(paramName)
+	if param == "" {
+		return defaultValue, nil
+	}
+
+	value, err := strconv.Atoi(param)
+	if err != nil || value < min || (max >= 0 && value > max) {
+		if max < 0 {
+			return 0, fmt.Errorf("Url param '%s' should be a number from %d ", paramName, min)
+		}
+		return 0, fmt.Errorf("Url param '%s' should be a number from %d to %d ", paramName, min, max)
+	}
+	return value, nil
+}
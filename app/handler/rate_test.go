@@ -3,8 +3,11 @@ package handler
 import (
 	"bytes"
 	"database/sql"
+	"database/sql/driver"
 	"encoding/json"
+	"errors"
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
@@ -15,6 +18,7 @@ import (
 	"regexp"
 	"spotHero/app/model"
 	"testing"
+	"time"
 )
 
 // Suite has gorm DB, sql DB  and db instances
@@ -33,12 +37,7 @@ func (s *Suite) SetupSuite() {
 	s.DB = DB
 	s.sqlDB = sqlDB
 
-	s.rate = model.Rate{
-		Days: "mon,tues,thurs",
-		Times: "0900-2100",
-		Tz:  "America/Chicago",
-		Price: 1500,
-	}
+	s.rate = rate(s, "mon,tues,thurs", "0900-2100", "America/Chicago", 1500)
 }
 
 func (s *Suite) AfterTest(_, _ string) {
@@ -51,8 +50,12 @@ func TestInit(t *testing.T) {
 
 // TestGetAllRatesHandler test the GetAllRates endpoint.
 func (s *Suite) TestGetAllRatesHandler() {
-	rows := s.mock.NewRows([]string{"days", "times", "tz", "price"}).AddRow(s.rate.Days, s.rate.Times, s.rate.Tz, s.rate.Price)
-	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `rates`")).WillReturnRows(rows)
+	s.expectFacility(1)
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM `rates`")).
+		WillReturnRows(s.mock.NewRows([]string{"count"}).AddRow(1))
+	rows := s.mock.NewRows(rateColumns).AddRow(rateRow(1, s.rate)...)
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `rates` WHERE facility_id = ? ORDER BY id LIMIT 100")).WillReturnRows(rows)
+	s.expectCurrency("USD")
 
 	req, err := http.NewRequest("GET", "/rates", nil)
 	assert.NoError(s.T(), err)
@@ -63,48 +66,437 @@ func (s *Suite) TestGetAllRatesHandler() {
 
 	var rates []model.Rate
 	rates = append(rates, s.rate)
-	jsonRates, marshalError := json.Marshal(rates)
+	rates[0].ID = 1
+	jsonRates, marshalError := json.Marshal(model.NewRatesOutput(rates, "USD").Rates)
 	assert.NoError(s.T(), marshalError)
 	assert.Equal(s.T(), httpRec.Body.String(), string(jsonRates) )
+	assert.Equal(s.T(), httpRec.Header().Get("X-Total-Count"), "1")
+	assert.Empty(s.T(), httpRec.Header().Get("Link"))
+}
+
+// TestGetAllRatesFiltered test the GetAllRates endpoint filters, sorts & paginates the rates.
+func (s *Suite) TestGetAllRatesFiltered() {
+	s.expectFacility(1)
+	where := "WHERE days & ? != 0 AND tz = ? AND price >= ? AND price <= ? AND (start_time <= ? AND end_time > ?) AND facility_id = ?"
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM `rates` " + where)).
+		WithArgs(int(1<<time.Monday), "America/Chicago", 1000, 2000, 600, 600, 1).
+		WillReturnRows(s.mock.NewRows([]string{"count"}).AddRow(3))
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `rates` " + where + " ORDER BY price DESC, id LIMIT 1 OFFSET 1")).
+		WithArgs(int(1<<time.Monday), "America/Chicago", 1000, 2000, 600, 600, 1).
+		WillReturnRows(s.mock.NewRows(rateColumns).AddRow(rateRow(1, s.rate)...))
+	s.expectCurrency("USD")
+
+	req, err := http.NewRequest("GET", "/rates?day=mon&tz=America/Chicago&min_price=1000&max_price=2000&covers=1000&sort=price&order=desc&limit=1&offset=1", nil)
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+
+	GetAllRates(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusOK)
+	assert.Equal(s.T(), httpRec.Header().Get("X-Total-Count"), "3")
+	assert.Equal(s.T(), httpRec.Header().Get("Link"), `</rates?covers=1000&day=mon&limit=1&max_price=2000&min_price=1000&offset=2&order=desc&sort=price&tz=America%2FChicago>; rel="next"`)
 }
 
-// TestPutRateInsert test to update the already stored rate
-func (s *Suite) TestPutRateInsert(){
+// TestGetAllRatesInvalidQuery should respond 400 for the invalid query params.
+func (s *Suite) TestGetAllRatesInvalidQuery() {
+	for _, query := range []string{"day=funday", "min_price=ten", "covers=25", "sort=color", "order=up", "limit=0", "limit=1001", "offset=-1"} {
+		s.expectFacility(1)
+		req, err := http.NewRequest("GET", "/rates?"+query, nil)
+		assert.NoError(s.T(), err)
+		httpRec := httptest.NewRecorder()
+
+		GetAllRates(s.DB, httpRec, req)
+		assert.Equal(s.T(), httpRec.Code, http.StatusBadRequest, query)
+	}
+}
+
+// TestUpsertRateInsert test to insert the new rate
+func (s *Suite) TestUpsertRateInsert(){
+	s.expectFacility(1)
 	s.mock.ExpectBegin()
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `rates` WHERE facility_id = ? AND tz = ?")).
+		WithArgs(1, s.rate.Tz).
+		WillReturnRows(s.mock.NewRows(rateColumns))
 	s.mock.ExpectExec("INSERT INTO `rates`(.*)").
-		WithArgs(s.rate.Days, s.rate.Times, s.rate.Tz, s.rate.Price).
-		WillReturnResult(sqlmock.NewResult(0, 1))
+		WithArgs(1, s.rate.Days, s.rate.StartTime, s.rate.EndTime, s.rate.Tz, s.rate.Price, s.rate.Priority, model.KindFlat, 0, model.RoundUp, nil, nil, nil, nil).
+		WillReturnResult(sqlmock.NewResult(7, 1))
+	s.expectAudit(model.AuditCreate, 7)
 	s.mock.ExpectCommit()
+	s.expectCurrency("USD")
 
 	jsonRate, marshalError := json.Marshal(s.rate)
 	assert.NoError(s.T(), marshalError)
 
+	req, err := http.NewRequest("POST", "/rates", bytes.NewBuffer(jsonRate))
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+	UpsertRate(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusCreated)
+	assert.Equal(s.T(), httpRec.Body.String(), `{"id":7,"days":"mon,tues,thurs","times":"0900-2100","tz":"America/Chicago","price":{"amount":1500,"currency":"USD"}}`)
+}
+
+// TestUpsertRateUpdate test to update the already stored rate, keeping its id
+func (s *Suite) TestUpsertRateUpdate(){
+	s.expectFacility(1)
+	s.mock.ExpectBegin()
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `rates` WHERE facility_id = ? AND tz = ?")).
+		WithArgs(1, s.rate.Tz).
+		WillReturnRows(s.mock.NewRows(rateColumns).AddRow(rateRow(3, s.rate)...))
+	s.mock.ExpectExec(regexp.QuoteMeta("UPDATE `rates` SET")).
+		WithArgs(1, s.rate.Days, s.rate.StartTime, s.rate.EndTime, s.rate.Tz, 4000, s.rate.Priority, model.KindFlat, 0, model.RoundUp, nil, nil, nil, nil, 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	s.expectAudit(model.AuditUpdate, 3)
+	s.mock.ExpectCommit()
+	s.expectCurrency("USD")
 
-	req, err := http.NewRequest("PUT", "/rates", bytes.NewBuffer(jsonRate))
+	req, err := http.NewRequest("POST", "/rates", bytes.NewBufferString(`{"days":"mon,tues,thurs","times":"0900-2100","tz":"America/Chicago","price":4000}`))
 	assert.NoError(s.T(), err)
 	httpRec := httptest.NewRecorder()
-	PutRate(s.DB, httpRec, req)
+	UpsertRate(s.DB, httpRec, req)
 	assert.Equal(s.T(), httpRec.Code, http.StatusCreated)
-	assert.Equal(s.T(), httpRec.Body.String(), string(jsonRate) )
+	assert.Equal(s.T(), httpRec.Body.String(), `{"id":3,"days":"mon,tues,thurs","times":"0900-2100","tz":"America/Chicago","price":{"amount":4000,"currency":"USD"}}`)
+}
+
+// TestUpsertRateOverlap should respond 409 for the rate overlapping a stored rate with the same priority.
+func (s *Suite) TestUpsertRateOverlap(){
+	s.expectFacility(1)
+	s.mock.ExpectBegin()
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `rates` WHERE facility_id = ? AND tz = ?")).
+		WithArgs(1, s.rate.Tz).
+		WillReturnRows(s.mock.NewRows(rateColumns).AddRow(rateRow(1, s.rate)...))
+	s.mock.ExpectRollback()
+
+	req, err := http.NewRequest("POST", "/rates", bytes.NewBufferString(`{"days":"thurs","times":"2000-2300","tz":"America/Chicago","price":500}`))
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+	UpsertRate(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusConflict)
+	assert.Equal(s.T(), httpRec.Body.String(), `{"code":"conflict","error":"rate (thurs 2000-2300 America/Chicago) overlaps rate (mon,tues,thurs 0900-2100 America/Chicago) on thurs, with the same priority 0"}`)
+}
+
+// TestUpsertRateInvalid should respond 422 with the invalid fields and not store the rate.
+func (s *Suite) TestUpsertRateInvalid(){
+	s.expectFacility(1)
+	req, err := http.NewRequest("POST", "/rates", bytes.NewBufferString(`{"days":"mon","times":"2100-0900","tz":"America/Chicago","price":1500}`))
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+	UpsertRate(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusUnprocessableEntity)
+	assert.Equal(s.T(), httpRec.Body.String(), `{"code":"validation_failed","error":"validation failed","fields":[{"field":"times","message":"end '0900' isn't after start '2100'"}]}`)
+}
+
+// TestUpsertRateUnknownField should respond 400 for the unknown json field.
+func (s *Suite) TestUpsertRateUnknownField(){
+	s.expectFacility(1)
+	req, err := http.NewRequest("POST", "/rates", bytes.NewBufferString(`{"days":"mon","times":"0900-2100","tz":"America/Chicago","price":1500,"cost":1}`))
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+	UpsertRate(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusBadRequest)
 }
 
-func (s *Suite) TestPutRateUpdate(){
-	s.rate.Price = 4000
+// TestPutRates test to replace all the stored rates with the rate list.
+func (s *Suite) TestPutRates(){
+	s.expectFacility(1)
+	other := rate(s, "sat", "0000-2400", "America/Chicago", 3000)
 	s.mock.ExpectBegin()
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `rates`")).
+		WillReturnRows(s.mock.NewRows(rateColumns).AddRow(rateRow(4, s.rate)...).AddRow(rateRow(5, other)...))
+	s.mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `rates`")).WillReturnResult(sqlmock.NewResult(0, 2))
 	s.mock.ExpectExec("INSERT INTO `rates`(.*)").
-		WithArgs(s.rate.Days, s.rate.Times, s.rate.Tz, s.rate.Price).
-		WillReturnResult(sqlmock.NewResult(0, 1))
+		WithArgs(1, s.rate.Days, s.rate.StartTime, s.rate.EndTime, s.rate.Tz, 1600, s.rate.Priority, model.KindFlat, 0, model.RoundUp, nil, nil, nil, nil, 4).
+		WillReturnResult(sqlmock.NewResult(4, 1))
+	s.mock.ExpectExec("INSERT INTO `rates`(.*)").
+		WithArgs(1, model.Weekdays(1<<time.Wednesday), 360, 1080, "America/Chicago", 1750, 0, model.KindFlat, 0, model.RoundUp, nil, nil, nil, nil).
+		WillReturnResult(sqlmock.NewResult(6, 1))
+	s.expectAudit(model.AuditDelete, 5)
+	s.expectAudit(model.AuditUpdate, 4)
+	s.expectAudit(model.AuditCreate, 6)
 	s.mock.ExpectCommit()
+	s.expectCurrency("USD")
 
-	jsonRate, marshalError := json.Marshal(s.rate)
+	body := `{"rates":[{"days":"mon,tues,thurs","times":"0900-2100","tz":"America/Chicago","price":1600},` +
+		`{"days":"wed","times":"0600-1800","tz":"America/Chicago","price":1750}]}`
+	req, err := http.NewRequest("PUT", "/rates", bytes.NewBufferString(body))
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+	PutRates(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusOK)
+	assert.Equal(s.T(), httpRec.Body.String(), `{"rates":[{"id":4,"days":"mon,tues,thurs","times":"0900-2100","tz":"America/Chicago","price":{"amount":1600,"currency":"USD"}},`+
+		`{"id":6,"days":"wed","times":"0600-1800","tz":"America/Chicago","price":{"amount":1750,"currency":"USD"}}]}`)
+}
+
+// TestPutRatesRollback test the stored rates are kept when the new rate list can't be stored.
+func (s *Suite) TestPutRatesRollback(){
+	s.expectFacility(1)
+	s.mock.ExpectBegin()
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `rates`")).WillReturnRows(s.mock.NewRows(rateColumns))
+	s.mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `rates`")).WillReturnResult(sqlmock.NewResult(0, 5))
+	s.mock.ExpectExec("INSERT INTO `rates`(.*)").WillReturnError(errors.New("disk I/O error"))
+	s.mock.ExpectRollback()
+
+	jsonRates, marshalError := json.Marshal(model.Rates{Rates: []model.Rate{s.rate}})
 	assert.NoError(s.T(), marshalError)
 
-	req, err := http.NewRequest("PUT", "/rates", bytes.NewBuffer(jsonRate))
+	req, err := http.NewRequest("PUT", "/rates", bytes.NewBuffer(jsonRates))
 	assert.NoError(s.T(), err)
 	httpRec := httptest.NewRecorder()
-	PutRate(s.DB, httpRec, req)
-	assert.Equal(s.T(), httpRec.Code, http.StatusCreated)
-	assert.Equal(s.T(), httpRec.Body.String(), string(jsonRate) )
+	PutRates(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusInternalServerError)
+}
+
+// TestPutRatesInvalid should respond 422 with the invalid fields of the whole list and not touch the stored rates.
+func (s *Suite) TestPutRatesInvalid(){
+	s.expectFacility(1)
+	body := `{"rates":[{"days":"mon","times":"0900-2100","tz":"America/Chicago","price":1500},` +
+		`{"days":"mon","times":"0900-2100","tz":"America/Chicago","price":2000},` +
+		`{"days":"tue","times":"0900-2100","tz":"America/Chicago","price":-1}]}`
+	req, err := http.NewRequest("PUT", "/rates", bytes.NewBufferString(body))
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+	PutRates(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusUnprocessableEntity)
+	assert.Equal(s.T(), httpRec.Body.String(), `{"code":"validation_failed","error":"validation failed","fields":[{"field":"rates[1]","message":"duplicates rates[0]"},{"field":"rates[2].price","message":"can't be negative"}]}`)
+}
+
+// TestPutRatesOverlap should respond 409 for the rate list having overlapping rates.
+func (s *Suite) TestPutRatesOverlap(){
+	s.expectFacility(1)
+	body := `{"rates":[{"days":"mon","times":"0900-2100","tz":"America/Chicago","price":1500},` +
+		`{"days":"mon,tue","times":"2000-2200","tz":"America/Chicago","price":2000}]}`
+	req, err := http.NewRequest("PUT", "/rates", bytes.NewBufferString(body))
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+	PutRates(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusConflict)
+}
+
+// TestGetRate test the GetRate endpoint.
+func (s *Suite) TestGetRate(){
+	s.expectFacility(1)
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `rates` WHERE facility_id = ? AND `rates`.`id` = ?")).
+		WithArgs(1, 2).
+		WillReturnRows(s.mock.NewRows(rateColumns).AddRow(rateRow(2, s.rate)...))
+	s.expectCurrency("USD")
+
+	req, err := http.NewRequest("GET", "/rates/2", nil)
+	assert.NoError(s.T(), err)
+	req = mux.SetURLVars(req, map[string]string{"id": "2"})
+	httpRec := httptest.NewRecorder()
+	GetRate(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusOK)
+	assert.Equal(s.T(), httpRec.Body.String(), `{"id":2,"days":"mon,tues,thurs","times":"0900-2100","tz":"America/Chicago","price":{"amount":1500,"currency":"USD"}}`)
+}
+
+// TestGetRateNotFound should respond 404 for the unknown id.
+func (s *Suite) TestGetRateNotFound(){
+	s.expectFacility(1)
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `rates` WHERE facility_id = ? AND `rates`.`id` = ?")).
+		WithArgs(1, 9).
+		WillReturnRows(s.mock.NewRows(rateColumns))
+
+	req, err := http.NewRequest("GET", "/rates/9", nil)
+	assert.NoError(s.T(), err)
+	req = mux.SetURLVars(req, map[string]string{"id": "9"})
+	httpRec := httptest.NewRecorder()
+	GetRate(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusNotFound)
+	assert.Equal(s.T(), httpRec.Body.String(), `{"code":"not_found","error":"rate '9' not found"}`)
+}
+
+// TestPatchRate test only the supplied fields of the rate are changed.
+func (s *Suite) TestPatchRate(){
+	s.expectFacility(1)
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `rates` WHERE facility_id = ? AND `rates`.`id` = ?")).
+		WithArgs(1, 2).
+		WillReturnRows(s.mock.NewRows(rateColumns).AddRow(rateRow(2, s.rate)...))
+	s.mock.ExpectBegin()
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `rates` WHERE facility_id = ? AND tz = ?")).
+		WithArgs(1, s.rate.Tz).
+		WillReturnRows(s.mock.NewRows(rateColumns).AddRow(rateRow(2, s.rate)...))
+	s.mock.ExpectExec(regexp.QuoteMeta("UPDATE `rates` SET")).
+		WithArgs(1, s.rate.Days, s.rate.StartTime, model.TimeOfDay(22*60), s.rate.Tz, 1800, s.rate.Priority, model.KindFlat, 0, model.RoundUp, nil, nil, nil, nil, 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	s.expectAudit(model.AuditUpdate, 2)
+	s.mock.ExpectCommit()
+	s.expectCurrency("USD")
+
+	req, err := http.NewRequest("PATCH", "/rates/2", bytes.NewBufferString(`{"times":"0900-2200","price":1800}`))
+	assert.NoError(s.T(), err)
+	req = mux.SetURLVars(req, map[string]string{"id": "2"})
+	httpRec := httptest.NewRecorder()
+	PatchRate(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusOK)
+	assert.Equal(s.T(), httpRec.Body.String(), `{"id":2,"days":"mon,tues,thurs","times":"0900-2200","tz":"America/Chicago","price":{"amount":1800,"currency":"USD"}}`)
+}
+
+// TestPatchRateInvalid should respond 422 for the patch making the rate invalid.
+func (s *Suite) TestPatchRateInvalid(){
+	s.expectFacility(1)
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `rates` WHERE facility_id = ? AND `rates`.`id` = ?")).
+		WithArgs(1, 2).
+		WillReturnRows(s.mock.NewRows(rateColumns).AddRow(rateRow(2, s.rate)...))
+
+	req, err := http.NewRequest("PATCH", "/rates/2", bytes.NewBufferString(`{"times":"0900-0800"}`))
+	assert.NoError(s.T(), err)
+	req = mux.SetURLVars(req, map[string]string{"id": "2"})
+	httpRec := httptest.NewRecorder()
+	PatchRate(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusUnprocessableEntity)
+}
+
+// TestDeleteRate test the rate is deleted by id.
+func (s *Suite) TestDeleteRate(){
+	s.expectFacility(1)
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `rates` WHERE facility_id = ? AND `rates`.`id` = ?")).
+		WithArgs(1, 2).
+		WillReturnRows(s.mock.NewRows(rateColumns).AddRow(rateRow(2, s.rate)...))
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec(regexp.QuoteMeta("DELETE FROM `rates` WHERE `rates`.`id` = ?")).
+		WithArgs(2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	s.expectAudit(model.AuditDelete, 2)
+	s.mock.ExpectCommit()
+
+	req, err := http.NewRequest("DELETE", "/rates/2", nil)
+	assert.NoError(s.T(), err)
+	req = mux.SetURLVars(req, map[string]string{"id": "2"})
+	httpRec := httptest.NewRecorder()
+	DeleteRate(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusNoContent)
+}
+
+// TestDeleteRateNotFound should respond 404 for the unknown id.
+func (s *Suite) TestDeleteRateNotFound(){
+	s.expectFacility(1)
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `rates` WHERE facility_id = ? AND `rates`.`id` = ?")).
+		WithArgs(1, 9).
+		WillReturnRows(s.mock.NewRows(rateColumns))
+
+	req, err := http.NewRequest("DELETE", "/rates/9", nil)
+	assert.NoError(s.T(), err)
+	req = mux.SetURLVars(req, map[string]string{"id": "9"})
+	httpRec := httptest.NewRecorder()
+	DeleteRate(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusNotFound)
+}
+
+// rateColumns are the columns of the rates table.
+var rateColumns = []string{"id", "facility_id", "days", "start_time", "end_time", "tz", "price", "priority", "kind", "increment", "rounding", "tiers", "effective_from", "effective_to", "vehicles"}
+
+// rate returns the rate parsed from the wire format values.
+func rate(s *Suite, days, times, tz string, price int) model.Rate {
+	rate, err := model.NewRate(days, times, tz, price)
+	require.NoError(s.T(), err)
+	rate.FacilityID = model.DefaultFacilityID
+	return rate
+}
+
+// rateRow returns the rates table row of the rate.
+func rateRow(id int, rate model.Rate) []driver.Value {
+	tiers, _ := rate.Tiers.Value()
+	vehicles, _ := rate.Vehicles.Value()
+	return []driver.Value{id, int(rate.FacilityID), int(rate.Days), int(rate.StartTime), int(rate.EndTime), rate.Tz, rate.Price, rate.Priority, string(rate.Kind), rate.Increment, string(rate.Rounding), tiers, rate.EffectiveFrom, rate.EffectiveTo, vehicles}
+}
+
+// expectCaps expects the price caps query, returning the caps.
+func (s *Suite) expectCaps(caps model.PriceCaps) {
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `price_caps` WHERE id = ? LIMIT 1")).
+		WillReturnRows(s.mock.NewRows([]string{"id", "daily_max", "weekly_max", "min_charge"}).
+			AddRow(1, caps.DailyMax, caps.WeeklyMax, caps.MinCharge))
+}
+
+// expectAudit expects the rate audit entry of the anonymous actor's change.
+func (s *Suite) expectAudit(action string, rateID int) {
+	s.mock.ExpectExec("INSERT INTO `rate_audits`(.*)").
+		WithArgs(sqlmock.AnyArg(), "anonymous", sqlmock.AnyArg(), action, rateID, 1, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+}
+
+// overrideColumns are the columns of the calendar_overrides table.
+var overrideColumns = []string{"id", "facility_id", "name", "start_date", "end_date", "multiplier", "rates"}
+
+// testTariff is the tariff of the facility returned by the quote queries, of the default facility unless set.
+// The enabled surge reads the facility capacity.
+type testTariff struct {
+	Facility  int
+	Rates     []model.Rate
+	Caps      model.PriceCaps
+	Products  []model.Product
+	Taxes     []model.TaxRule
+	Surge     model.Surge
+	Capacity  int
+	Overrides []model.CalendarOverride
+	Currency  model.Currency
+}
+
+// expectTariff expects the tariff queries of the facility quote in their order, returning the tariff.
+func (s *Suite) expectTariff(tariff testTariff) {
+	facility := tariff.Facility
+	if facility == 0 {
+		facility = model.DefaultFacilityID
+	}
+
+	rates := s.mock.NewRows(rateColumns)
+	for i, rate := range tariff.Rates {
+		rates.AddRow(rateRow(i+1, rate)...)
+	}
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `rates` WHERE facility_id = ?")).
+		WithArgs(facility).
+		WillReturnRows(rates)
+
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `price_caps` WHERE id = ? LIMIT 1")).
+		WithArgs(facility).
+		WillReturnRows(s.mock.NewRows([]string{"id", "daily_max", "weekly_max", "min_charge"}).
+			AddRow(facility, tariff.Caps.DailyMax, tariff.Caps.WeeklyMax, tariff.Caps.MinCharge))
+
+	products := s.mock.NewRows(productColumns)
+	for _, product := range tariff.Products {
+		products.AddRow(productRow(product)...)
+	}
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `products` WHERE facility_id = ?")).
+		WithArgs(facility).
+		WillReturnRows(products)
+
+	taxes := s.mock.NewRows(taxColumns)
+	for _, rule := range tariff.Taxes {
+		taxes.AddRow(rule.ID, rule.Name, rule.Kind, rule.BasisPoints, rule.Amount, rule.Currency, rule.FacilityID, rule.Jurisdiction)
+	}
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `tax_rules` WHERE facility_id = ? OR jurisdiction IN (SELECT `jurisdiction` FROM `facilities` WHERE id = ? AND jurisdiction <> '')")).
+		WithArgs(facility, facility).
+		WillReturnRows(taxes)
+
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `surges` WHERE id = ? LIMIT 1")).
+		WithArgs(facility).
+		WillReturnRows(s.mock.NewRows([]string{"id", "enabled", "bands", "min_multiplier", "max_multiplier"}).
+			AddRow(facility, tariff.Surge.Enabled, tariff.Surge.Bands, tariff.Surge.MinMultiplier, tariff.Surge.MaxMultiplier))
+	if tariff.Surge.Enabled {
+		s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `facilities` WHERE `facilities`.`id` = ?")).
+			WithArgs(facility).
+			WillReturnRows(s.mock.NewRows(facilityColumns).AddRow(facility, "Lot", "", "America/Chicago", tariff.Capacity, "USD"))
+	}
+
+	overrides := s.mock.NewRows(overrideColumns)
+	for i, override := range tariff.Overrides {
+		rates, _ := override.Rates.Value()
+		overrides.AddRow(i+1, facility, override.Name, override.StartDate, override.EndDate, override.Multiplier, rates)
+	}
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `calendar_overrides` WHERE facility_id = ? AND start_date <= ? AND end_date >= ?")).
+		WithArgs(facility, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(overrides)
+
+	currency := tariff.Currency
+	if currency == "" {
+		currency = model.DefaultCurrency
+	}
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT `currency` FROM `facilities` WHERE id = ? LIMIT 1")).
+		WithArgs(facility).
+		WillReturnRows(s.mock.NewRows([]string{"currency"}).AddRow(string(currency)))
+}
+
+// expectCurrency expects the currency query of the facility, returning the currency.
+func (s *Suite) expectCurrency(currency model.Currency) {
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT `currency` FROM `facilities` WHERE id = ? LIMIT 1")).
+		WillReturnRows(s.mock.NewRows([]string{"currency"}).AddRow(string(currency)))
 }
 
 // GetDatabase: set the sql mock and gorm v=based DB for testing.
@@ -125,4 +517,71 @@ func GetDatabase(s *Suite) (sqlmock.Sqlmock, *gorm.DB, *sql.DB){
 	require.NoError(s.T(), err)
 
 	return mock, DB, sqlDB
-}
\ No newline at end of file
+}
+// TestGetScheduledRates test the rate changes after the from param are listed in time order, with the replaced version.
+func (s *Suite) TestGetScheduledRates(){
+	s.expectFacility(1)
+	raised := s.rate
+	raisedFrom := time.Date(2015, time.August, 1, 0, 0, 0, 0, time.UTC)
+	raised.EffectiveFrom = &raisedFrom
+	expiring := rate(s, "wed", "0600-1800", "America/Chicago", 1750)
+	expiringTo := time.Date(2015, time.July, 15, 0, 0, 0, 0, time.UTC)
+	expiring.EffectiveTo = &expiringTo
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `rates`")).
+		WillReturnRows(s.mock.NewRows(rateColumns).AddRow(rateRow(1, s.rate)...).AddRow(rateRow(2, raised)...).AddRow(rateRow(3, expiring)...))
+	s.expectCurrency("USD")
+
+	req, err := http.NewRequest("GET", "/rates/scheduled?from=2015-07-01T00:00:00Z", nil)
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+	GetScheduledRates(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusOK)
+	assert.JSONEq(s.T(), `[
+		{"at":"2015-07-15T00:00:00Z","change":"expires","rate":{"id":3,"days":"wed","times":"0600-1800","tz":"America/Chicago","effective_to":"2015-07-15T00:00:00Z","price":{"amount":1750,"currency":"USD"}}},
+		{"at":"2015-08-01T00:00:00Z","change":"effective","rate":{"id":2,"days":"mon,tues,thurs","times":"0900-2100","tz":"America/Chicago","effective_from":"2015-08-01T00:00:00Z","price":{"amount":1500,"currency":"USD"}},"replaces":1}
+	]`, httpRec.Body.String())
+}
+
+// TestUpsertRateAuditActor test the audit entry is recorded with the actor & request id headers, echoing the request id.
+func (s *Suite) TestUpsertRateAuditActor(){
+	s.expectFacility(1)
+	s.mock.ExpectBegin()
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `rates` WHERE facility_id = ? AND tz = ?")).
+		WithArgs(1, s.rate.Tz).
+		WillReturnRows(s.mock.NewRows(rateColumns))
+	s.mock.ExpectExec("INSERT INTO `rates`(.*)").WillReturnResult(sqlmock.NewResult(7, 1))
+	s.mock.ExpectExec("INSERT INTO `rate_audits`(.*)").
+		WithArgs(sqlmock.AnyArg(), "ops", "req-42", model.AuditCreate, 7, 1, s.rate.Days, s.rate.StartTime, s.rate.EndTime, s.rate.Tz, nil,
+			`{"id":7,"days":"mon,tues,thurs","times":"0900-2100","tz":"America/Chicago","price":1500}`).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	s.mock.ExpectCommit()
+	s.expectCurrency("USD")
+
+	req, err := http.NewRequest("POST", "/rates", bytes.NewBufferString(`{"days":"mon,tues,thurs","times":"0900-2100","tz":"America/Chicago","price":1500}`))
+	assert.NoError(s.T(), err)
+	req.Header.Set(ActorHeader, "ops")
+	req.Header.Set(RequestIDHeader, "req-42")
+	httpRec := httptest.NewRecorder()
+	UpsertRate(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusCreated)
+	assert.Equal(s.T(), "req-42", httpRec.Header().Get(RequestIDHeader))
+}
+
+// TestGetRateAudit test the audit entries are filtered by the time range & rate key.
+func (s *Suite) TestGetRateAudit(){
+	s.expectFacility(1)
+	after := `{"id":7,"days":"mon,tues,thurs","times":"0900-2100","tz":"America/Chicago","price":1500}`
+	at := time.Date(2015, time.July, 1, 10, 0, 0, 0, time.UTC)
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `rate_audits` WHERE facility_id = ? AND at >= ? AND at < ? AND days = ? AND (start_time = ? AND end_time = ?) AND tz = ? ORDER BY id LIMIT 100")).
+		WithArgs(1, time.Date(2015, time.July, 1, 0, 0, 0, 0, time.UTC), time.Date(2015, time.July, 2, 0, 0, 0, 0, time.UTC), s.rate.Days, s.rate.StartTime, s.rate.EndTime, s.rate.Tz).
+		WillReturnRows(s.mock.NewRows([]string{"id", "at", "actor", "request_id", "action", "rate_id", "facility_id", "days", "start_time", "end_time", "tz", "before", "after"}).
+			AddRow(1, at, "ops", "req-42", model.AuditCreate, 7, 1, int(s.rate.Days), int(s.rate.StartTime), int(s.rate.EndTime), s.rate.Tz, nil, after))
+
+	req, err := http.NewRequest("GET", "/rates/audit?from=2015-07-01T00:00:00Z&to=2015-07-02T00:00:00Z&days=mon,tues,thurs&times=0900-2100&tz=America/Chicago", nil)
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+	GetRateAudit(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusOK)
+	assert.Equal(s.T(), httpRec.Body.String(), `[{"id":1,"at":"2015-07-01T10:00:00Z","actor":"ops","request_id":"req-42","action":"create","rate_id":7,"facility_id":1,`+
+		`"days":"mon,tues,thurs","times":"0900-2100","tz":"America/Chicago","before":null,"after":`+after+`}]`)
+}
@@ -0,0 +1,237 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"io"
+	"net/http"
+	"spotHero/app/model"
+	"spotHero/app/pricing"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// errFacilityFull is returned on booking the stay when every space of the facility is reserved at some instant of the stay.
+var errFacilityFull = errors.New("facility is full")
+
+// errNoCapacity is returned on booking the stay at the facility without any space, e.g. the default facility of the legacy rates.
+var errNoCapacity = errors.New("facility has no capacity")
+
+// CreateReservation return the booking handler which reserves a space of the facility for the stay, at the price quoted by the pricing engine.
+// The use of the promo code is counted by the booking.
+// The facility row is locked first, so that the concurrent bookings of the facility are checked against the capacity one after the other.
+func CreateReservation(engine *pricing.Engine) func(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
+	return func(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
+		facility, ok := facilityID(db, w, r)
+		if !ok {
+			return
+		}
+		reservationInput := model.ReservationInput{}
+
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&reservationInput); err != nil {
+			respondError(w, http.StatusBadRequest, ErrCodeInvalidBody, err.Error())
+			return
+		}
+
+		defer func(Body io.ReadCloser) {
+			err := Body.Close()
+			if err != nil {
+
+			}
+		}(r.Body)
+
+		reservation, validationErr := reservationInput.Validate()
+		if validationErr != nil {
+			respondValidationError(w, validationErr.(*model.ValidationError))
+			return
+		}
+		reservation.FacilityID = facility
+
+		var promo *model.PromoCode
+		if reservation.Promo != "" {
+			var promoErr error
+			if promo, promoErr = findPromo(db, reservation.Promo, facility, time.Now()); promoErr != nil {
+				respondPromoError(w, promoErr)
+				return
+			}
+		}
+
+		// locking in the quoted price of the stay
+		quote, err := quoteFacility(db, engine, facility, reservation.Vehicle, promo, reservation.Start, reservation.End)
+		if errors.Is(err, pricing.ErrUnavailable) {
+			respondError(w, http.StatusUnprocessableEntity, ErrCodeUnavailable, err.Error())
+			return
+		}
+
+		if err != nil {
+			respondPromoError(w, err)
+			return
+		}
+		reservation.Price, reservation.Total, reservation.Currency = quote.Price, quote.Total, quote.Currency
+
+		var capacity int
+		bookErr := db.Transaction(func(tx *gorm.DB) error {
+			// the no-op update locks the facility row, it's the write lock of the whole DB on SQLite
+			locked := tx.Model(&model.Facility{}).Where("id = ?", facility).UpdateColumn("capacity", gorm.Expr("capacity"))
+			if locked.Error != nil {
+				return locked.Error
+			}
+			if locked.RowsAffected == 0 {
+				return gorm.ErrRecordNotFound
+			}
+
+			stored := model.Facility{}
+			if err := tx.First(&stored, facility).Error; err != nil {
+				return err
+			}
+			capacity = stored.Capacity
+			if capacity <= 0 {
+				return errNoCapacity
+			}
+
+			var overlapping []model.Reservation
+			if err := tx.Where("facility_id = ? AND start_at < ? AND end_at > ?", facility, reservation.End, reservation.Start).Find(&overlapping).Error; err != nil {
+				return err
+			}
+			if model.PeakOccupancy(overlapping, reservation.Start, reservation.End) >= capacity {
+				return errFacilityFull
+			}
+
+			// counting the use of the promo code, unless the concurrent bookings reached its limit
+			if promo != nil {
+				used := tx.Model(&model.PromoCode{}).Where("id = ? AND (max_uses = 0 OR uses < max_uses)", promo.ID).UpdateColumn("uses", gorm.Expr("uses + 1"))
+				if used.Error != nil {
+					return used.Error
+				}
+				if used.RowsAffected == 0 {
+					return &model.PromoError{Code: promo.Code, Reason: fmt.Sprintf("reached its limit of %d uses", promo.MaxUses)}
+				}
+			}
+			return tx.Create(&reservation).Error
+		})
+
+		if errors.Is(bookErr, errNoCapacity) {
+			respondError(w, http.StatusUnprocessableEntity, ErrCodeUnavailable, fmt.Sprintf("facility '%d' has no capacity configured, set its capacity to book the stay", facility))
+			return
+		}
+
+		if errors.Is(bookErr, errFacilityFull) {
+			respondError(w, http.StatusConflict, ErrCodeConflict, fmt.Sprintf("facility '%d' has no space left for the stay, all %d spaces are reserved", facility, capacity))
+			return
+		}
+
+		var promoErr *model.PromoError
+		if errors.As(bookErr, &promoErr) {
+			respondError(w, http.StatusUnprocessableEntity, ErrCodeInvalidPromo, promoErr.Error())
+			return
+		}
+
+		if errors.Is(bookErr, gorm.ErrRecordNotFound) {
+			respondError(w, http.StatusNotFound, ErrCodeNotFound, fmt.Sprintf("facility '%d' not found", facility))
+			return
+		}
+
+		if bookErr != nil {
+			respondError(w, http.StatusInternalServerError, ErrCodeInternal, bookErr.Error())
+			return
+		}
+		respondJSON(w, http.StatusCreated, reservation)
+	}
+}
+
+// GetReservations api endpoints to get the reservations of the facility ordered by start, the from & to (ISO-8601) query params
+// keep the reservations overlapping the time range, paginated by limit & offset.
+func GetReservations(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
+	facility, ok := facilityID(db, w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	filtered := db.Where("facility_id = ?", facility)
+
+	for _, bound := range []struct{ param, condition string }{{"from", "end_at > ?"}, {"to", "start_at < ?"}} {
+		if query.Get(bound.param) == "" {
+			continue
+		}
+		boundTime, err := validateTimeParam(r.URL, bound.param)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, ErrCodeInvalidParam, err.Error())
+			return
+		}
+		filtered = filtered.Where(bound.condition, boundTime.UTC())
+	}
+
+	limit, err := intParam(query, "limit", defaultRatesLimit, 1, maxRatesLimit)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, ErrCodeInvalidParam, err.Error())
+		return
+	}
+	offset, err := intParam(query, "offset", 0, 0, -1)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, ErrCodeInvalidParam, err.Error())
+		return
+	}
+
+	reservations := []model.Reservation{}
+	if err := filtered.Order("start_at, id").Limit(limit).Offset(offset).Find(&reservations).Error; err != nil {
+		respondError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, reservations)
+}
+
+// GetReservation api endpoints to get the reservation of the facility by id.
+func GetReservation(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
+	reservation := getReservationOr404(db, w, r)
+	if reservation == nil {
+		return
+	}
+	respondJSON(w, http.StatusOK, reservation)
+}
+
+// DeleteReservation api endpoints to cancel the reservation of the facility by id, its space is free again.
+func DeleteReservation(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
+	reservation := getReservationOr404(db, w, r)
+	if reservation == nil {
+		return
+	}
+
+	if err := db.Delete(reservation).Error; err != nil {
+		respondError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getReservationOr404 gets the reservation of the facility & id path params if exists, or respond the 404 error otherwise
+func getReservationOr404(db *gorm.DB, w http.ResponseWriter, r *http.Request) *model.Reservation {
+	facility, ok := facilityID(db, w, r)
+	if !ok {
+		return nil
+	}
+
+	param := mux.Vars(r)["id"]
+	id, parseErr := strconv.ParseUint(param, 10, 64)
+	if parseErr != nil {
+		respondError(w, http.StatusNotFound, ErrCodeNotFound, fmt.Sprintf("reservation '%s' not found", param))
+		return nil
+	}
+
+	reservation := model.Reservation{}
+	if err := db.Where("facility_id = ?", facility).First(&reservation, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(w, http.StatusNotFound, ErrCodeNotFound, fmt.Sprintf("reservation '%d' not found", id))
+		} else {
+			respondError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error())
+		}
+		return nil
+	}
+	return &reservation
+}
@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"spotHero/app/model"
+	"spotHero/app/pricing"
+	"sync"
+	"time"
+)
+
+// reservationColumns are the columns of the reservations table.
+var reservationColumns = []string{"id", "facility_id", "start_at", "end_at", "vehicle", "price", "promo", "total", "currency", "created_at"}
+
+// expectBooking expects the quote of the stay at the suite rate, the facility lock & the overlapping reservations of the booking.
+func (s *Suite) expectBooking(capacity int, overlapping *sqlmock.Rows) {
+	s.expectTariff(testTariff{Rates: []model.Rate{s.rate}})
+
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec(regexp.QuoteMeta("UPDATE `facilities` SET `capacity`=capacity WHERE id = ?")).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `facilities` WHERE `facilities`.`id` = ?")).
+		WithArgs(1).
+		WillReturnRows(s.mock.NewRows(facilityColumns).AddRow(1, "Default", "", "America/Chicago", capacity, "USD"))
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE facility_id = ? AND start_at < ? AND end_at > ?")).
+		WithArgs(1, time.Date(2015, time.July, 2, 20, 0, 0, 0, time.UTC), time.Date(2015, time.July, 2, 15, 0, 0, 0, time.UTC)).
+		WillReturnRows(overlapping)
+}
+
+// TestCreateReservation test the reservation is booked at the quoted price.
+func (s *Suite) TestCreateReservation(){
+	s.expectFacility(1)
+	s.expectBooking(2, s.mock.NewRows(reservationColumns).
+		AddRow(1, 1, time.Date(2015, time.July, 2, 14, 0, 0, 0, time.UTC), time.Date(2015, time.July, 2, 16, 0, 0, 0, time.UTC), "standard", 1500, "", 1500, "USD", time.Now()))
+	s.mock.ExpectExec("INSERT INTO `reservations`(.*)").
+		WithArgs(1, time.Date(2015, time.July, 2, 15, 0, 0, 0, time.UTC), time.Date(2015, time.July, 2, 20, 0, 0, 0, time.UTC), model.VehicleStandard, 1500, "", 1500, model.Currency("USD"), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	s.mock.ExpectCommit()
+
+	req, err := http.NewRequest("POST", "/reservations", bytes.NewBufferString(`{"start":"2015-07-02T10:00:00-05:00","end":"2015-07-02T15:00:00-05:00"}`))
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+	CreateReservation(pricing.NewEngine(pricing.PolicyStrict))(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusCreated)
+	assert.Regexp(s.T(), `^\{"id":2,"facility_id":1,"start":"2015-07-02T15:00:00Z","end":"2015-07-02T20:00:00Z","vehicle":"standard","price":1500,"total":1500,"currency":"USD","created_at":".*"\}$`, httpRec.Body.String())
+}
+
+// TestCreateReservationFull should respond 409 when every space is reserved at some instant of the stay.
+func (s *Suite) TestCreateReservationFull(){
+	s.expectFacility(1)
+	s.expectBooking(1, s.mock.NewRows(reservationColumns).
+		AddRow(1, 1, time.Date(2015, time.July, 2, 19, 0, 0, 0, time.UTC), time.Date(2015, time.July, 2, 22, 0, 0, 0, time.UTC), "standard", 1500, "", 1500, "USD", time.Now()))
+	s.mock.ExpectRollback()
+
+	req, err := http.NewRequest("POST", "/reservations", bytes.NewBufferString(`{"start":"2015-07-02T10:00:00-05:00","end":"2015-07-02T15:00:00-05:00"}`))
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+	CreateReservation(pricing.NewEngine(pricing.PolicyStrict))(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusConflict)
+	assert.Equal(s.T(), httpRec.Body.String(), `{"code":"conflict","error":"facility '1' has no space left for the stay, all 1 spaces are reserved"}`)
+}
+
+// TestCreateReservationNoCapacity should respond 422 for the facility without any capacity, e.g. the default facility of the legacy rates.
+func (s *Suite) TestCreateReservationNoCapacity(){
+	s.expectFacility(1)
+	s.expectTariff(testTariff{Rates: []model.Rate{s.rate}})
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec(regexp.QuoteMeta("UPDATE `facilities` SET `capacity`=capacity WHERE id = ?")).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `facilities` WHERE `facilities`.`id` = ?")).
+		WithArgs(1).
+		WillReturnRows(s.mock.NewRows(facilityColumns).AddRow(1, "Default", "", "America/Chicago", 0, "USD"))
+	s.mock.ExpectRollback()
+
+	req, err := http.NewRequest("POST", "/reservations", bytes.NewBufferString(`{"start":"2015-07-02T10:00:00-05:00","end":"2015-07-02T15:00:00-05:00"}`))
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+	CreateReservation(pricing.NewEngine(pricing.PolicyStrict))(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusUnprocessableEntity)
+	assert.Equal(s.T(), httpRec.Body.String(), `{"code":"unavailable","error":"facility '1' has no capacity configured, set its capacity to book the stay"}`)
+}
+
+// TestCreateReservationInvalid should respond 422 with every invalid field of the reservation.
+func (s *Suite) TestCreateReservationInvalid(){
+	s.expectFacility(1)
+	req, err := http.NewRequest("POST", "/reservations", bytes.NewBufferString(`{"end":"2015-07-02T15:00:00-05:00","vehicle":"bus"}`))
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+	CreateReservation(pricing.NewEngine(pricing.PolicyStrict))(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusUnprocessableEntity)
+	assert.Equal(s.T(), httpRec.Body.String(), `{"code":"validation_failed","error":"validation failed","fields":[{"field":"start","message":"is required"},`+
+		`{"field":"vehicle","message":"unknown vehicle 'bus', isn't compact, standard, oversize or motorcycle"}]}`)
+}
+
+// TestDeleteReservationNotFound should respond 404 for the unknown reservation id.
+func (s *Suite) TestDeleteReservationNotFound(){
+	s.expectFacility(1)
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE facility_id = ? AND `reservations`.`id` = ?")).
+		WithArgs(1, 9).
+		WillReturnRows(s.mock.NewRows(reservationColumns))
+
+	req, err := http.NewRequest("DELETE", "/reservations/9", nil)
+	assert.NoError(s.T(), err)
+	req = mux.SetURLVars(req, map[string]string{"id": "9"})
+	httpRec := httptest.NewRecorder()
+	DeleteReservation(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusNotFound)
+	assert.Equal(s.T(), httpRec.Body.String(), `{"code":"not_found","error":"reservation '9' not found"}`)
+}
+
+// TestCreateReservationConcurrent test the concurrent bookings don't reserve more spaces than the facility capacity.
+func (s *Suite) TestCreateReservationConcurrent(){
+	db, err := gorm.Open(sqlite.Open(filepath.Join(s.T().TempDir(), "rates.db")), &gorm.Config{})
+	require.NoError(s.T(), err)
+	require.NoError(s.T(), model.DBMigrate(db))
+	require.NoError(s.T(), db.Create(&model.Facility{Name: "Default", Tz: "America/Chicago", Capacity: 3}).Error)
+	require.NoError(s.T(), db.Create(&s.rate).Error)
+
+	book := CreateReservation(pricing.NewEngine(pricing.PolicyStrict))
+	codes := make([]int, 10)
+	var wg sync.WaitGroup
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			body := fmt.Sprintf(`{"start":"2015-07-02T%02d:00:00-05:00","end":"2015-07-02T20:00:00-05:00"}`, 10+i%5)
+			req, _ := http.NewRequest("POST", "/reservations", bytes.NewBufferString(body))
+			httpRec := httptest.NewRecorder()
+			book(db, httpRec, req)
+			codes[i] = httpRec.Code
+		}(i)
+	}
+	wg.Wait()
+
+	created := 0
+	for _, code := range codes {
+		if code == http.StatusCreated {
+			created++
+		} else {
+			assert.Equal(s.T(), http.StatusConflict, code)
+		}
+	}
+	assert.Equal(s.T(), 3, created)
+
+	var count int64
+	require.NoError(s.T(), db.Model(&model.Reservation{}).Count(&count).Error)
+	assert.Equal(s.T(), int64(3), count)
+}
+
+// TestCreateReservationPromoLimit should respond 422 when the concurrent bookings used the promo code up to its limit.
+func (s *Suite) TestCreateReservationPromoLimit(){
+	s.expectFacility(1)
+	s.expectPromo("ONCE", s.mock.NewRows(promoColumns).AddRow(4, "ONCE", "fixed_off", 0, 500, "USD", nil, nil, 1, 0, nil))
+	s.expectBooking(2, s.mock.NewRows(reservationColumns))
+	s.mock.ExpectExec(regexp.QuoteMeta("UPDATE `promo_codes` SET `uses`=uses + 1 WHERE id = ? AND (max_uses = 0 OR uses < max_uses)")).
+		WithArgs(4).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	s.mock.ExpectRollback()
+
+	req, err := http.NewRequest("POST", "/reservations", bytes.NewBufferString(`{"start":"2015-07-02T10:00:00-05:00","end":"2015-07-02T15:00:00-05:00","promo":"once"}`))
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+	CreateReservation(pricing.NewEngine(pricing.PolicyStrict))(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusUnprocessableEntity)
+	assert.Equal(s.T(), httpRec.Body.String(), `{"code":"invalid_promo","error":"promo code 'ONCE' reached its limit of 1 uses"}`)
+}
@@ -0,0 +1,192 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"net/http"
+	"spotHero/app/exchange"
+	"spotHero/app/model"
+	"spotHero/app/pricing"
+	"sort"
+	"sync"
+	"time"
+)
+
+// SearchResult is the quote of one facility for the searched stay, the price is missing for the unavailable facility.
+// The amounts are in the searched currency, converted from the facility currency.
+type SearchResult struct {
+	Facility  model.Facility `json:"facility"`
+	Available bool           `json:"available"`
+	Price     *model.Money   `json:"price,omitempty"`
+	Total     *model.Money   `json:"total,omitempty"`
+	Reason    string         `json:"reason,omitempty"`
+}
+
+// Search return the search handler which quotes the query start and end time param of the vehicle param against every facility,
+// quoting at most parallelism facilities at the same time. The prices are converted to the currency param (USD by default) with the
+// exchange rate of the provider, the facility without an exchange rate is unavailable. The results are sorted by the sort (price or id)
+// & order (asc or desc) params, the unavailable facilities last, and filtered by the max_price param (in the minor unit of the currency)
+// which drops the unavailable facilities as well.
+func Search(engine *pricing.Engine, provider exchange.Provider, parallelism int) func(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	return func(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
+		startTime, startErr := validateTimeParam(r.URL, "start")
+		endTime, endErr := validateTimeParam(r.URL, "end")
+
+		if startErr != nil {
+			respondError(w, http.StatusBadRequest, ErrCodeInvalidParam, startErr.Error())
+			return
+		}
+
+		if endErr != nil {
+			respondError(w, http.StatusBadRequest, ErrCodeInvalidParam, endErr.Error())
+			return
+		}
+
+		if endTime.Before(*startTime) {
+			respondError(w, http.StatusBadRequest, ErrCodeInvalidParam, "Url param 'end' is before 'start' ")
+			return
+		}
+
+		vehicle, vehicleErr := validateVehicleParam(r.URL)
+		if vehicleErr != nil {
+			respondError(w, http.StatusBadRequest, ErrCodeInvalidParam, vehicleErr.Error())
+			return
+		}
+
+		currency, currencyErr := validateCurrencyParam(r.URL)
+		if currencyErr != nil {
+			respondError(w, http.StatusBadRequest, ErrCodeInvalidParam, currencyErr.Error())
+			return
+		}
+		if currency == "" {
+			currency = model.DefaultCurrency
+		}
+
+		query := r.URL.Query()
+		maxPrice, err := intParam(query, "max_price", -1, 0, -1)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, ErrCodeInvalidParam, err.Error())
+			return
+		}
+
+		sortBy := query.Get("sort")
+		switch sortBy {
+		case "":
+			sortBy = "price"
+		case "price", "id":
+		default:
+			respondError(w, http.StatusBadRequest, ErrCodeInvalidParam, fmt.Sprintf("Url param 'sort' has unknown value '%s' ", sortBy))
+			return
+		}
+
+		order := query.Get("order")
+		if order != "" && order != "asc" && order != "desc" {
+			respondError(w, http.StatusBadRequest, ErrCodeInvalidParam, fmt.Sprintf("Url param 'order' has unknown value '%s' ", order))
+			return
+		}
+
+		facilities := []model.Facility{}
+		if err := db.Order("id").Find(&facilities).Error; err != nil {
+			respondError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error())
+			return
+		}
+
+		results, err := quoteFacilities(db, engine, provider, facilities, vehicle, currency, *startTime, *endTime, parallelism)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error())
+			return
+		}
+
+		if maxPrice >= 0 {
+			filtered := []SearchResult{}
+			for _, result := range results {
+				if result.Available && result.Price.Amount <= maxPrice {
+					filtered = append(filtered, result)
+				}
+			}
+			results = filtered
+		}
+
+		sortSearchResults(results, sortBy, order == "desc")
+		respondJSON(w, http.StatusOK, results)
+	}
+}
+
+// quoteFacilities quotes the stay of the vehicle class against every facility, at most parallelism facilities at the same time, with the prices
+// converted to the currency. The results are in the order of the facilities, the error of any facility other than the unavailable stay
+// or the missing exchange rate is returned.
+func quoteFacilities(db *gorm.DB, engine *pricing.Engine, provider exchange.Provider, facilities []model.Facility, vehicle model.VehicleClass, currency model.Currency,
+	startTime, endTime time.Time, parallelism int) ([]SearchResult, error) {
+	results := make([]SearchResult, len(facilities))
+	errs := make([]error, len(facilities))
+	slots := make(chan struct{}, parallelism)
+
+	var wg sync.WaitGroup
+	for i := range facilities {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+
+			results[i].Facility = facilities[i]
+			quote, err := quoteFacility(db, engine, facilities[i].ID, vehicle, nil, startTime, endTime)
+			if errors.Is(err, pricing.ErrUnavailable) {
+				results[i].Reason = err.Error()
+				return
+			}
+			if err != nil {
+				errs[i] = err
+				return
+			}
+
+			// converting the price from the facility currency, so that the prices of every facility compare
+			price, total := model.Money{Amount: quote.Price, Currency: currency}, model.Money{Amount: quote.Total, Currency: currency}
+			if quote.Currency != currency {
+				rate, err := provider.Rate(quote.Currency, currency)
+				if errors.Is(err, exchange.ErrNoRate) {
+					results[i].Reason = err.Error()
+					return
+				}
+				if err != nil {
+					errs[i] = err
+					return
+				}
+				price.Amount, total.Amount = rate.Convert(quote.Price), rate.Convert(quote.Total)
+			}
+			results[i].Available, results[i].Price, results[i].Total = true, &price, &total
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// sortSearchResults sorts the results by the price or facility id, the unavailable facilities are last by their id.
+func sortSearchResults(results []SearchResult, sortBy string, descending bool) {
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Available != b.Available {
+			return a.Available
+		}
+		if sortBy == "price" && a.Available && a.Price.Amount != b.Price.Amount {
+			return (a.Price.Amount < b.Price.Amount) != descending
+		}
+		if sortBy == "id" {
+			return (a.Facility.ID < b.Facility.ID) != descending
+		}
+		return a.Facility.ID < b.Facility.ID
+	})
+}
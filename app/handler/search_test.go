@@ -0,0 +1,167 @@
+package handler
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"regexp"
+	"spotHero/app/exchange"
+	"spotHero/app/model"
+	"spotHero/app/pricing"
+	"sync"
+	"time"
+)
+
+// expectSearchFacilities expects the facilities query of the search and the tariff queries of every facility, in the order of the facilities.
+func (s *Suite) expectSearchFacilities(rates ...[]model.Rate) {
+	currencies := make([]model.Currency, len(rates))
+	for i := range currencies {
+		currencies[i] = "USD"
+	}
+	s.expectSearchFacilitiesIn(currencies, rates...)
+}
+
+// expectSearchFacilitiesIn expects the search queries of the facilities in the currencies.
+func (s *Suite) expectSearchFacilitiesIn(currencies []model.Currency, rates ...[]model.Rate) {
+	facilities := s.mock.NewRows(facilityColumns)
+	for i := range rates {
+		facilities.AddRow(i+1, "Lot", "", "America/Chicago", 50, string(currencies[i]))
+	}
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `facilities` ORDER BY id")).WillReturnRows(facilities)
+
+	for i, facilityRates := range rates {
+		s.expectTariff(testTariff{Facility: i + 1, Rates: facilityRates, Currency: currencies[i]})
+	}
+}
+
+// TestSearch test every facility is quoted, sorted by price with the unavailable facility last.
+func (s *Suite) TestSearch(){
+	s.expectSearchFacilities(
+		[]model.Rate{rate(s, "fri,sat,sun", "0900-2100", "America/Chicago", 2000)},
+		[]model.Rate{rate(s, "sat", "0600-2200", "America/Chicago", 1500)},
+		nil,
+	)
+
+	req, err := http.NewRequest("GET", "/search?start=2015-07-04T10:00:00-05:00&end=2015-07-04T15:00:00-05:00", nil)
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+
+	Search(pricing.NewEngine(pricing.PolicyStrict), testExchange, 1)(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusOK)
+	assert.Equal(s.T(), httpRec.Body.String(), `[`+
+		`{"facility":{"id":2,"name":"Lot","address":"","tz":"America/Chicago","capacity":50,"currency":"USD"},"available":true,"price":{"amount":1500,"currency":"USD"},"total":{"amount":1500,"currency":"USD"}},`+
+		`{"facility":{"id":1,"name":"Lot","address":"","tz":"America/Chicago","capacity":50,"currency":"USD"},"available":true,"price":{"amount":2000,"currency":"USD"},"total":{"amount":2000,"currency":"USD"}},`+
+		`{"facility":{"id":3,"name":"Lot","address":"","tz":"America/Chicago","capacity":50,"currency":"USD"},"available":false,"reason":"unavailable: no rate covers the stay"}]`)
+}
+
+// TestSearchMaxPrice test the facilities above the max price and the unavailable facilities are dropped.
+func (s *Suite) TestSearchMaxPrice(){
+	s.expectSearchFacilities(
+		[]model.Rate{rate(s, "fri,sat,sun", "0900-2100", "America/Chicago", 2000)},
+		[]model.Rate{rate(s, "sat", "0600-2200", "America/Chicago", 1500)},
+		nil,
+	)
+
+	req, err := http.NewRequest("GET", "/search?start=2015-07-04T10:00:00-05:00&end=2015-07-04T15:00:00-05:00&max_price=1800&order=desc", nil)
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+
+	Search(pricing.NewEngine(pricing.PolicyStrict), testExchange, 1)(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusOK)
+	assert.Equal(s.T(), httpRec.Body.String(), `[{"facility":{"id":2,"name":"Lot","address":"","tz":"America/Chicago","capacity":50,"currency":"USD"},"available":true,"price":{"amount":1500,"currency":"USD"},"total":{"amount":1500,"currency":"USD"}}]`)
+}
+
+// TestSearchCurrency test the prices of every facility are converted to the currency before the sort & max price,
+// the facility without an exchange rate is unavailable.
+func (s *Suite) TestSearchCurrency(){
+	s.expectSearchFacilitiesIn([]model.Currency{"EUR", "USD", "GBP"},
+		[]model.Rate{rate(s, "sat", "0600-2200", "America/Chicago", 1500)},
+		[]model.Rate{rate(s, "sat", "0600-2200", "America/Chicago", 1600)},
+		[]model.Rate{rate(s, "sat", "0600-2200", "America/Chicago", 1000)},
+	)
+
+	req, err := http.NewRequest("GET", "/search?start=2015-07-04T10:00:00-05:00&end=2015-07-04T15:00:00-05:00&currency=usd", nil)
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+
+	Search(pricing.NewEngine(pricing.PolicyStrict), testExchange, 1)(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusOK)
+	assert.Equal(s.T(), httpRec.Body.String(), `[`+
+		`{"facility":{"id":2,"name":"Lot","address":"","tz":"America/Chicago","capacity":50,"currency":"USD"},"available":true,"price":{"amount":1600,"currency":"USD"},"total":{"amount":1600,"currency":"USD"}},`+
+		`{"facility":{"id":1,"name":"Lot","address":"","tz":"America/Chicago","capacity":50,"currency":"EUR"},"available":true,"price":{"amount":1667,"currency":"USD"},"total":{"amount":1667,"currency":"USD"}},`+
+		`{"facility":{"id":3,"name":"Lot","address":"","tz":"America/Chicago","capacity":50,"currency":"GBP"},"available":false,"reason":"no exchange rate from GBP to USD"}]`)
+}
+
+// countingProvider is the exchange rate provider recording the peak of the rates asked at the same time.
+type countingProvider struct {
+	exchange.Provider
+	mu      sync.Mutex
+	running int
+	peak    int
+}
+
+// Rate returns the exchange rate of the provider, holding it for a while so that the concurrent calls overlap.
+func (p *countingProvider) Rate(from, to model.Currency) (exchange.Rate, error) {
+	p.mu.Lock()
+	p.running++
+	if p.running > p.peak {
+		p.peak = p.running
+	}
+	p.mu.Unlock()
+
+	time.Sleep(10 * time.Millisecond)
+
+	p.mu.Lock()
+	p.running--
+	p.mu.Unlock()
+	return p.Provider.Rate(from, to)
+}
+
+// TestSearchParallel test the facilities quoted at the same time are all in the results, sorted by price,
+// and at most parallelism facilities are quoted at the same time.
+func (s *Suite) TestSearchParallel(){
+	db, err := gorm.Open(sqlite.Open(filepath.Join(s.T().TempDir(), "rates.db")), &gorm.Config{})
+	require.NoError(s.T(), err)
+	require.NoError(s.T(), model.DBMigrate(db))
+	for i := 1; i <= 6; i++ {
+		require.NoError(s.T(), db.Create(&model.Facility{Name: "Lot", Tz: "America/Chicago", Capacity: 50, Currency: "EUR"}).Error)
+		facilityRate := rate(s, "sat", "0600-2200", "America/Chicago", 3000-100*i)
+		facilityRate.FacilityID = uint(i)
+		require.NoError(s.T(), db.Create(&facilityRate).Error)
+	}
+
+	req, err := http.NewRequest("GET", "/search?start=2015-07-04T10:00:00-05:00&end=2015-07-04T15:00:00-05:00", nil)
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+
+	provider := &countingProvider{Provider: testExchange}
+	Search(pricing.NewEngine(pricing.PolicyStrict), provider, 2)(db, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusOK)
+	assert.LessOrEqual(s.T(), provider.peak, 2)
+
+	euro, err := testExchange.Rate("EUR", "USD")
+	require.NoError(s.T(), err)
+	var results []SearchResult
+	require.NoError(s.T(), json.Unmarshal(httpRec.Body.Bytes(), &results))
+	require.Len(s.T(), results, 6)
+	for i, result := range results {
+		assert.Equal(s.T(), result.Facility.ID, uint(6-i))
+		assert.Equal(s.T(), *result.Price, model.Money{Amount: euro.Convert(2400 + 100*i), Currency: "USD"})
+	}
+}
+
+// TestSearchInvalidParams should respond 400 for the unknown sort.
+func (s *Suite) TestSearchInvalidParams(){
+	req, err := http.NewRequest("GET", "/search?start=2015-07-04T10:00:00-05:00&end=2015-07-04T15:00:00-05:00&sort=distance", nil)
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+
+	Search(pricing.NewEngine(pricing.PolicyStrict), testExchange, 1)(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusBadRequest)
+	assert.Equal(s.T(), httpRec.Body.String(), `{"code":"invalid_param","error":"Url param 'sort' has unknown value 'distance' "}`)
+}
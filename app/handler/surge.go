@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"encoding/json"
+	"gorm.io/gorm"
+	"io"
+	"net/http"
+	"spotHero/app/model"
+	"spotHero/app/pricing"
+	"time"
+)
+
+// GetSurge api endpoints to get the occupancy surge settings of the facility, the surge is off by default.
+func GetSurge(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
+	facility, ok := facilityID(db, w, r)
+	if !ok {
+		return
+	}
+
+	surge := model.Surge{}
+	if err := db.Where("id = ?", facility).Limit(1).Find(&surge).Error; err != nil {
+		respondError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, surge)
+}
+
+// PutSurge api endpoints to replace the occupancy surge settings of the facility, "enabled": false is the kill switch of the surge.
+func PutSurge(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
+	facility, ok := facilityID(db, w, r)
+	if !ok {
+		return
+	}
+
+	surge := model.Surge{}
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&surge); err != nil {
+		respondError(w, http.StatusBadRequest, ErrCodeInvalidBody, err.Error())
+		return
+	}
+
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+
+		}
+	}(r.Body)
+
+	if validationErr := surge.Validate(); validationErr != nil {
+		respondValidationError(w, validationErr.(*model.ValidationError))
+		return
+	}
+
+	// the surge settings are stored as the single row of the facility, keyed on the facility id
+	surge.ID = facility
+	if err := db.Save(&surge).Error; err != nil {
+		respondError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, surge)
+}
+
+// occupancyCounter returns the counter of the most reservations of the facility holding a space at the same instant of the stay.
+func occupancyCounter(db *gorm.DB, facility uint) pricing.OccupancyCounter {
+	return func(start, end time.Time) (int, error) {
+		var overlapping []model.Reservation
+		if err := db.Where("facility_id = ? AND start_at < ? AND end_at > ?", facility, end.UTC(), start.UTC()).Find(&overlapping).Error; err != nil {
+			return 0, err
+		}
+		return model.PeakOccupancy(overlapping, start, end), nil
+	}
+}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"bytes"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"spotHero/app/model"
+	"spotHero/app/pricing"
+	"time"
+)
+
+// TestGetPriceSurge return the price multiplied by the surge band of the facility occupancy over the stay.
+func (s *Suite) TestGetPriceSurge(){
+	s.expectFacility(1)
+	s.expectTariff(testTariff{
+		Rates: []model.Rate{s.rate},
+		Surge: model.Surge{Enabled: true, Bands: model.SurgeBands{{Occupancy: 50, Multiplier: 1.2}}, MaxMultiplier: 2},
+		Capacity: 2,
+	})
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `reservations` WHERE facility_id = ? AND start_at < ? AND end_at > ?")).
+		WithArgs(1, time.Date(2015, time.July, 2, 20, 0, 0, 0, time.UTC), time.Date(2015, time.July, 2, 15, 0, 0, 0, time.UTC)).
+		WillReturnRows(s.mock.NewRows(reservationColumns).
+			AddRow(1, 1, time.Date(2015, time.July, 2, 14, 0, 0, 0, time.UTC), time.Date(2015, time.July, 2, 16, 0, 0, 0, time.UTC), "standard", 1500, "", 1500, "USD", time.Now()))
+
+	req, err := http.NewRequest("GET", "/price?start=2015-07-02T10:00:00-05:00&end=2015-07-02T15:00:00-05:00", nil)
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+
+	GetPrice(pricing.NewEngine(pricing.PolicyStrict), testExchange)(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusOK)
+	assert.Equal(s.T(), httpRec.Body.String(), `{"price":{"amount":1800,"currency":"USD"},"total":{"amount":1800,"currency":"USD"}}`)
+}
+
+// TestPutSurge test the surge settings are stored with the facility id.
+func (s *Suite) TestPutSurge(){
+	s.expectFacility(1)
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec(regexp.QuoteMeta("UPDATE `surges` SET")).
+		WithArgs(true, `[{"occupancy":80,"multiplier":1.5}]`, 0.0, 2.0, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectCommit()
+
+	req, err := http.NewRequest("PUT", "/surge", bytes.NewBufferString(`{"enabled":true,"bands":[{"occupancy":80,"multiplier":1.5}],"max_multiplier":2}`))
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+	PutSurge(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusOK)
+	assert.Equal(s.T(), httpRec.Body.String(), `{"enabled":true,"bands":[{"occupancy":80,"multiplier":1.5}],"min_multiplier":0,"max_multiplier":2}`)
+}
+
+// TestPutSurgeInvalid should respond 422 with every invalid field of the surge settings.
+func (s *Suite) TestPutSurgeInvalid(){
+	s.expectFacility(1)
+	req, err := http.NewRequest("PUT", "/surge", bytes.NewBufferString(`{"enabled":true,"bands":[{"occupancy":80,"multiplier":-1}],"min_multiplier":-1}`))
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+	PutSurge(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusUnprocessableEntity)
+	assert.Equal(s.T(), httpRec.Body.String(), `{"code":"validation_failed","error":"validation failed","fields":[{"field":"bands[0].multiplier","message":"must be positive"},`+
+		`{"field":"min_multiplier","message":"can't be negative"}]}`)
+}
@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"io"
+	"net/http"
+	"spotHero/app/model"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// GetTaxes api endpoints to get the tax rules, of the facility & jurisdiction query params if any.
+func GetTaxes(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filtered := db
+
+	if query.Get("facility") != "" {
+		facility, err := intParam(query, "facility", 0, 1, -1)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, ErrCodeInvalidParam, err.Error())
+			return
+		}
+		filtered = filtered.Where("facility_id = ?", facility)
+	}
+	if jurisdiction := query.Get("jurisdiction"); jurisdiction != "" {
+		filtered = filtered.Where("jurisdiction = ?", jurisdiction)
+	}
+
+	rules := []model.TaxRule{}
+	if err := filtered.Order("id").Find(&rules).Error; err != nil {
+		respondError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, rules)
+}
+
+// CreateTax api endpoints to add the tax rule of the facility or jurisdiction.
+func CreateTax(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
+	rule, ok := decodeTax(db, w, r)
+	if !ok {
+		return
+	}
+
+	if err := db.Create(&rule).Error; err != nil {
+		respondError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusCreated, rule)
+}
+
+// GetTax api endpoints to get the tax rule by id.
+func GetTax(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
+	rule := getTaxOr404(db, w, r)
+	if rule == nil {
+		return
+	}
+	respondJSON(w, http.StatusOK, rule)
+}
+
+// PutTax api endpoints to replace the tax rule by id.
+func PutTax(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
+	stored := getTaxOr404(db, w, r)
+	if stored == nil {
+		return
+	}
+
+	rule, ok := decodeTax(db, w, r)
+	if !ok {
+		return
+	}
+	rule.ID = stored.ID
+
+	if err := db.Save(&rule).Error; err != nil {
+		respondError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error())
+		return
+	}
+	respondJSON(w, http.StatusOK, rule)
+}
+
+// DeleteTax api endpoints to delete the tax rule by id.
+func DeleteTax(db *gorm.DB, w http.ResponseWriter, r *http.Request) {
+	rule := getTaxOr404(db, w, r)
+	if rule == nil {
+		return
+	}
+
+	if err := db.Delete(rule).Error; err != nil {
+		respondError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodeTax decodes & validates the tax rule of the request body, the facility of the rule must exist, or respond the error otherwise
+func decodeTax(db *gorm.DB, w http.ResponseWriter, r *http.Request) (model.TaxRule, bool) {
+	ruleInput := model.TaxRuleInput{}
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&ruleInput); err != nil {
+		respondError(w, http.StatusBadRequest, ErrCodeInvalidBody, err.Error())
+		return model.TaxRule{}, false
+	}
+
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+
+		}
+	}(r.Body)
+
+	rule, validationErr := ruleInput.Validate()
+	if validationErr != nil {
+		respondValidationError(w, validationErr.(*model.ValidationError))
+		return model.TaxRule{}, false
+	}
+
+	if rule.FacilityID != 0 {
+		var facilities int64
+		if err := db.Model(&model.Facility{}).Where("id = ?", rule.FacilityID).Count(&facilities).Error; err != nil {
+			respondError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error())
+			return model.TaxRule{}, false
+		}
+		if facilities == 0 {
+			respondValidationError(w, &model.ValidationError{Fields: []model.FieldError{{Field: "facility_id", Message: fmt.Sprintf("facility '%d' not found", rule.FacilityID)}}})
+			return model.TaxRule{}, false
+		}
+	}
+	return rule, true
+}
+
+// getTaxOr404 gets the tax rule of the id path param if exists, or respond the 404 error otherwise
+func getTaxOr404(db *gorm.DB, w http.ResponseWriter, r *http.Request) *model.TaxRule {
+	param := mux.Vars(r)["id"]
+	id, parseErr := strconv.ParseUint(param, 10, 64)
+	if parseErr != nil {
+		respondError(w, http.StatusNotFound, ErrCodeNotFound, fmt.Sprintf("tax rule '%s' not found", param))
+		return nil
+	}
+
+	rule := model.TaxRule{}
+	if err := db.First(&rule, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(w, http.StatusNotFound, ErrCodeNotFound, fmt.Sprintf("tax rule '%d' not found", id))
+		} else {
+			respondError(w, http.StatusInternalServerError, ErrCodeInternal, err.Error())
+		}
+		return nil
+	}
+	return &rule
+}
@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"spotHero/app/model"
+	"spotHero/app/pricing"
+)
+
+// taxColumns are the columns of the tax_rules table.
+var taxColumns = []string{"id", "name", "kind", "basis_points", "amount", "currency", "facility_id", "jurisdiction"}
+
+// TestGetPriceTaxes return the taxes & fees of the facility & its jurisdiction charged on the price, and the total.
+func (s *Suite) TestGetPriceTaxes(){
+	s.expectFacility(1)
+	s.expectTariff(testTariff{
+		Rates: []model.Rate{s.rate},
+		Taxes: []model.TaxRule{
+			{ID: 1, Name: "Chicago parking tax", Kind: model.TaxPercent, BasisPoints: 2375, Jurisdiction: "chicago"},
+			{ID: 2, Name: "Service fee", Kind: model.TaxFlat, Amount: 199, FacilityID: 1},
+		},
+	})
+
+	req, err := http.NewRequest("GET", "/price?start=2015-07-02T15:00:00-05:00&end=2015-07-02T20:00:00-05:00", nil)
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+
+	GetPrice(pricing.NewEngine(pricing.PolicyStrict), testExchange)(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusOK)
+
+	var price Price
+	assert.NoError(s.T(), json.Unmarshal(httpRec.Body.Bytes(), &price))
+	assert.Equal(s.T(), price.Price, usd(1500))
+	// 23.75% of 1500 is 356.25 cents, rounded to 356
+	assert.Equal(s.T(), price.Taxes, []PriceTax{{Name: "Chicago parking tax", Amount: usd(356)}, {Name: "Service fee", Amount: usd(199)}})
+	assert.Equal(s.T(), price.Total, usd(2055))
+}
+
+// TestGetPriceTaxOtherCurrency should respond 422 when the flat tax rule isn't in the facility currency.
+func (s *Suite) TestGetPriceTaxOtherCurrency(){
+	s.expectFacility(1)
+	s.expectTariff(testTariff{
+		Rates:    []model.Rate{s.rate},
+		Taxes:    []model.TaxRule{{ID: 2, Name: "Service fee", Kind: model.TaxFlat, Amount: 199, Currency: "USD", FacilityID: 1}},
+		Currency: "EUR",
+	})
+
+	req, err := http.NewRequest("GET", "/price?start=2015-07-02T15:00:00-05:00&end=2015-07-02T20:00:00-05:00", nil)
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+
+	GetPrice(pricing.NewEngine(pricing.PolicyStrict), testExchange)(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusUnprocessableEntity)
+	assert.Equal(s.T(), httpRec.Body.String(), `{"code":"unavailable","error":"unavailable: tax 'Service fee' is in USD, the facility prices are in EUR"}`)
+}
+
+// TestCreateTax test the tax rule of the jurisdiction is added.
+func (s *Suite) TestCreateTax(){
+	s.mock.ExpectBegin()
+	s.mock.ExpectExec("INSERT INTO `tax_rules`(.*)").
+		WithArgs("Chicago parking tax", model.TaxPercent, 2375, 0, model.Currency(""), 0, "chicago").
+		WillReturnResult(sqlmock.NewResult(3, 1))
+	s.mock.ExpectCommit()
+
+	req, err := http.NewRequest("POST", "/taxes", bytes.NewBufferString(`{"name":"Chicago parking tax","kind":"percent","basis_points":2375,"jurisdiction":"chicago"}`))
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+	CreateTax(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusCreated)
+	assert.Equal(s.T(), httpRec.Body.String(), `{"id":3,"name":"Chicago parking tax","kind":"percent","basis_points":2375,"jurisdiction":"chicago"}`)
+}
+
+// TestCreateTaxUnknownFacility should respond 422 for the tax rule of the unknown facility.
+func (s *Suite) TestCreateTaxUnknownFacility(){
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM `facilities` WHERE id = ?")).
+		WithArgs(9).
+		WillReturnRows(s.mock.NewRows([]string{"count"}).AddRow(0))
+
+	req, err := http.NewRequest("POST", "/taxes", bytes.NewBufferString(`{"name":"Service fee","kind":"flat","amount":199,"facility_id":9}`))
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+	CreateTax(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusUnprocessableEntity)
+	assert.Equal(s.T(), httpRec.Body.String(), `{"code":"validation_failed","error":"validation failed","fields":[{"field":"facility_id","message":"facility '9' not found"}]}`)
+}
+
+// TestCreateTaxInvalid should respond 422 with every invalid field of the tax rule.
+func (s *Suite) TestCreateTaxInvalid(){
+	req, err := http.NewRequest("POST", "/taxes", bytes.NewBufferString(`{"name":"Tax","kind":"percent","basis_points":20000,"amount":5,"currency":"EUR","facility_id":1,"jurisdiction":"chicago"}`))
+	assert.NoError(s.T(), err)
+	httpRec := httptest.NewRecorder()
+	CreateTax(s.DB, httpRec, req)
+	assert.Equal(s.T(), httpRec.Code, http.StatusUnprocessableEntity)
+	assert.Equal(s.T(), httpRec.Body.String(), `{"code":"validation_failed","error":"validation failed","fields":[{"field":"basis_points","message":"must be 1 to 10000"},`+
+		`{"field":"amount","message":"is only for the flat rule"},{"field":"currency","message":"is only for the flat rule"},{"field":"facility_id","message":"either the facility or the jurisdiction is required"}]}`)
+}
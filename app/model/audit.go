@@ -0,0 +1,125 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Actions of the rate audit entries.
+const (
+	AuditCreate = "create"
+	AuditUpdate = "update"
+	AuditDelete = "delete"
+)
+
+// ErrAuditAppendOnly is returned on updating or deleting the rate audit entries.
+var ErrAuditAppendOnly = errors.New("rate audit is append only")
+
+// RateAudit struct for storing the append-only audit trail of the rate changes in DB, keyed on the rate facility, days, times & tz.
+// Before & after are the rate in the wire format, before is nil on create and after is nil on delete.
+type RateAudit struct {
+	ID         uint      `gorm:"primaryKey"`
+	At         time.Time `gorm:"not null;index"`
+	Actor      string    `gorm:"not null"`
+	RequestID  string    `gorm:"not null"`
+	Action     string    `gorm:"not null"`
+	RateID     uint      `gorm:"not null;index"`
+	FacilityID uint      `gorm:"not null;default:1;index"`
+	Days       Weekdays  `gorm:"not null;index:idx_rate_audit_key"`
+	StartTime  TimeOfDay `gorm:"not null;index:idx_rate_audit_key"`
+	EndTime    TimeOfDay `gorm:"not null;index:idx_rate_audit_key"`
+	Tz         string    `gorm:"not null;index:idx_rate_audit_key"`
+	Before     *string   `gorm:"type:text"`
+	After      *string   `gorm:"type:text"`
+}
+
+// BeforeUpdate rejects the update of the audit entry.
+func (a *RateAudit) BeforeUpdate(tx *gorm.DB) error {
+	return ErrAuditAppendOnly
+}
+
+// BeforeDelete rejects the delete of the audit entry.
+func (a *RateAudit) BeforeDelete(tx *gorm.DB) error {
+	return ErrAuditAppendOnly
+}
+
+// MarshalJSON writes the audit entry with the rate key in the wire format and the before & after rates as json.
+func (a RateAudit) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		ID         uint            `json:"id"`
+		At         time.Time       `json:"at"`
+		Actor      string          `json:"actor"`
+		RequestID  string          `json:"request_id"`
+		Action     string          `json:"action"`
+		RateID     uint            `json:"rate_id"`
+		FacilityID uint            `json:"facility_id"`
+		Days       string          `json:"days"`
+		Times      string          `json:"times"`
+		Tz         string          `json:"tz"`
+		Before     json.RawMessage `json:"before"`
+		After      json.RawMessage `json:"after"`
+	}{
+		ID:         a.ID,
+		At:         a.At,
+		Actor:      a.Actor,
+		RequestID:  a.RequestID,
+		Action:     a.Action,
+		RateID:     a.RateID,
+		FacilityID: a.FacilityID,
+		Days:       a.Days.String(),
+		Times:      TimeWindow{Start: a.StartTime, End: a.EndTime}.String(),
+		Tz:         a.Tz,
+		Before:     rawJSON(a.Before),
+		After:      rawJSON(a.After),
+	})
+}
+
+// rawJSON returns the stored json, or null.
+func rawJSON(data *string) json.RawMessage {
+	if data == nil {
+		return json.RawMessage("null")
+	}
+	return json.RawMessage(*data)
+}
+
+// Auditor records the rate changes made by the actor within the request.
+type Auditor struct {
+	Actor     string
+	RequestID string
+}
+
+// Record appends the audit entry of the rate change, before is nil on create and after is nil on delete.
+func (a Auditor) Record(tx *gorm.DB, action string, before, after *Rate) error {
+	audit := RateAudit{At: time.Now().UTC(), Actor: a.Actor, RequestID: a.RequestID, Action: action}
+
+	keyRate := after
+	if keyRate == nil {
+		keyRate = before
+	}
+	audit.RateID, audit.FacilityID, audit.Days, audit.StartTime, audit.EndTime, audit.Tz = keyRate.ID, keyRate.FacilityID, keyRate.Days, keyRate.StartTime, keyRate.EndTime, keyRate.Tz
+
+	var err error
+	if audit.Before, err = rateJSON(before); err != nil {
+		return err
+	}
+	if audit.After, err = rateJSON(after); err != nil {
+		return err
+	}
+	return tx.Create(&audit).Error
+}
+
+// rateJSON returns the rate in the wire format, or nil.
+func rateJSON(rate *Rate) (*string, error) {
+	if rate == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(rate)
+	if err != nil {
+		return nil, err
+	}
+	value := string(data)
+	return &value, nil
+}
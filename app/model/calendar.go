@@ -0,0 +1,230 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DateFormat is the format of the calendar override dates.
+const DateFormat = "2006-01-02"
+
+// CalendarOverride struct for storing the dated override of the weekday rates in DB, from start to end date inclusive.
+// On its dates, either the override rates replace the weekday rates or the weekday rate prices are multiplied, at its facility only.
+type CalendarOverride struct {
+	ID         uint          `gorm:"primaryKey"`
+	FacilityID uint          `gorm:"not null;default:1;index"`
+	Name       string        `gorm:"not null"`
+	StartDate  string        `gorm:"not null;index"`
+	EndDate    string        `gorm:"not null;index"`
+	Multiplier float64       `gorm:"not null;default:0"`
+	Rates      OverrideRates `gorm:"type:text"`
+}
+
+// OverrideRates is the rate set of the calendar override, stored as json in the DB.
+type OverrideRates []Rate
+
+// Value writes the rates as json into the DB.
+func (r OverrideRates) Value() (driver.Value, error) {
+	if len(r) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(r)
+	return string(data), err
+}
+
+// Scan reads the rates from the json stored in the DB.
+func (r *OverrideRates) Scan(value interface{}) error {
+	switch data := value.(type) {
+	case nil:
+		*r = nil
+		return nil
+	case string:
+		return json.Unmarshal([]byte(data), r)
+	case []byte:
+		return json.Unmarshal(data, r)
+	}
+	return fmt.Errorf("can't scan %T into override rates", value)
+}
+
+// CalendarOverrideInput is the wire format of the calendar override, the end date is the start date by default.
+type CalendarOverrideInput struct {
+	ID         uint        `json:"id,omitempty"`
+	Name       string      `json:"name"`
+	StartDate  string      `json:"start_date"`
+	EndDate    string      `json:"end_date,omitempty"`
+	Multiplier *float64    `json:"multiplier,omitempty"`
+	Rates      []RateInput `json:"rates,omitempty"`
+}
+
+// CalendarInput is the wire format of the calendar override list, as in the rates.json file style.
+type CalendarInput struct {
+	Overrides []CalendarOverrideInput `json:"overrides"`
+}
+
+// Calendar struct contains the list of calendar override.
+type Calendar struct {
+	Overrides []CalendarOverride `json:"overrides"`
+}
+
+// Covers check if the local date is within the override dates.
+func (o CalendarOverride) Covers(date time.Time) bool {
+	day := date.Format(DateFormat)
+	return o.StartDate <= day && day <= o.EndDate
+}
+
+// Overlaps check if the override dates share a day.
+func (o CalendarOverride) Overlaps(other CalendarOverride) bool {
+	return o.StartDate <= other.EndDate && other.StartDate <= o.EndDate
+}
+
+// Apply returns the weekday rate with the price & tier prices multiplied, rounded to the nearest cent.
+func (o CalendarOverride) Apply(rate Rate) Rate {
+	return rate.Scale(o.Multiplier)
+}
+
+// Input returns the calendar override in the wire format.
+func (o CalendarOverride) Input() CalendarOverrideInput {
+	input := CalendarOverrideInput{ID: o.ID, Name: o.Name, StartDate: o.StartDate, EndDate: o.EndDate}
+	if len(o.Rates) > 0 {
+		input.Rates = make([]RateInput, len(o.Rates))
+		for i, rate := range o.Rates {
+			input.Rates[i] = rate.Input()
+		}
+	} else {
+		multiplier := o.Multiplier
+		input.Multiplier = &multiplier
+	}
+	return input
+}
+
+// MarshalJSON writes the calendar override in the wire format.
+func (o CalendarOverride) MarshalJSON() ([]byte, error) {
+	return json.Marshal(o.Input())
+}
+
+// Validate check every field of the calendar override input and returns the override, or the ValidationError with all the invalid fields.
+// The override has either the multiplier or the rates, the rate days are every weekday by default.
+func (in CalendarOverrideInput) Validate() (CalendarOverride, error) {
+	validationErr := &ValidationError{}
+	override := CalendarOverride{Name: strings.TrimSpace(in.Name)}
+
+	if override.Name == "" {
+		validationErr.add("name", "is required")
+	}
+
+	if in.StartDate == "" {
+		validationErr.add("start_date", "is required")
+	} else if _, err := time.Parse(DateFormat, in.StartDate); err != nil {
+		validationErr.add("start_date", fmt.Sprintf("'%s' isn't as per YYYY-MM-DD format", in.StartDate))
+	} else {
+		override.StartDate, override.EndDate = in.StartDate, in.StartDate
+	}
+
+	if in.EndDate != "" {
+		if _, err := time.Parse(DateFormat, in.EndDate); err != nil {
+			validationErr.add("end_date", fmt.Sprintf("'%s' isn't as per YYYY-MM-DD format", in.EndDate))
+		} else if override.StartDate != "" && in.EndDate < override.StartDate {
+			validationErr.add("end_date", fmt.Sprintf("'%s' is before start date '%s'", in.EndDate, override.StartDate))
+		} else {
+			override.EndDate = in.EndDate
+		}
+	}
+
+	switch {
+	case in.Multiplier == nil && len(in.Rates) == 0:
+		validationErr.add("multiplier", "either multiplier or rates is required")
+	case in.Multiplier != nil && len(in.Rates) > 0:
+		validationErr.add("multiplier", "isn't allowed with the rates")
+	case in.Multiplier != nil:
+		if *in.Multiplier <= 0 {
+			validationErr.add("multiplier", "must be positive")
+		} else {
+			override.Multiplier = *in.Multiplier
+		}
+	default:
+		ratesErr := &ValidationError{}
+		for i, rateInput := range in.Rates {
+			if strings.TrimSpace(rateInput.Days) == "" {
+				rateInput.Days = AllWeekdays.String()
+			}
+			rate, err := rateInput.Validate()
+			if err != nil {
+				ratesErr.addAll(fmt.Sprintf("rates[%d]", i), err.(*ValidationError))
+				continue
+			}
+			override.Rates = append(override.Rates, rate)
+		}
+		if len(ratesErr.Fields) > 0 {
+			validationErr.Fields = append(validationErr.Fields, ratesErr.Fields...)
+		} else if err := CheckOverlaps(override.Rates); err != nil {
+			validationErr.add("rates", err.Error())
+		}
+	}
+
+	if err := validationErr.orNil(); err != nil {
+		return CalendarOverride{}, err
+	}
+	return override, nil
+}
+
+// Validate check every override of the input list, field names of the errors are prefixed with the override index e.g. "overrides[1].name".
+func (in CalendarInput) Validate() ([]CalendarOverride, error) {
+	validationErr := &ValidationError{}
+	if in.Overrides == nil {
+		validationErr.add("overrides", "is required")
+	}
+
+	overrides := make([]CalendarOverride, 0, len(in.Overrides))
+	for i, overrideInput := range in.Overrides {
+		override, err := overrideInput.Validate()
+		if err != nil {
+			validationErr.addAll(fmt.Sprintf("overrides[%d]", i), err.(*ValidationError))
+			continue
+		}
+		overrides = append(overrides, override)
+	}
+
+	if err := validationErr.orNil(); err != nil {
+		return nil, err
+	}
+	return overrides, nil
+}
+
+// CalendarOverlapError is returned when two calendar overrides share a date.
+type CalendarOverlapError struct {
+	Override CalendarOverride
+	Other    CalendarOverride
+}
+
+// Error describes the clash of the two overrides.
+func (e *CalendarOverlapError) Error() string {
+	return fmt.Sprintf("override '%s' (%s to %s) overlaps override '%s' (%s to %s)",
+		e.Override.Name, e.Override.StartDate, e.Override.EndDate, e.Other.Name, e.Other.StartDate, e.Other.EndDate)
+}
+
+// CheckCalendarOverlap returns the CalendarOverlapError if the override shares a date with any other override,
+// the stored override (non-zero id) is skipped.
+func CheckCalendarOverlap(override CalendarOverride, overrides []CalendarOverride) error {
+	for _, other := range overrides {
+		if override.ID != 0 && override.ID == other.ID {
+			continue
+		}
+		if override.Overlaps(other) {
+			return &CalendarOverlapError{Override: override, Other: other}
+		}
+	}
+	return nil
+}
+
+// CheckCalendarOverlaps returns the CalendarOverlapError for the first two overrides of the list sharing a date.
+func CheckCalendarOverlaps(overrides []CalendarOverride) error {
+	for i := range overrides {
+		if err := CheckCalendarOverlap(overrides[i], overrides[:i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
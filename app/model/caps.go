@@ -0,0 +1,24 @@
+package model
+
+// PriceCaps contains the caps applied on the price of a stay after the segments are priced, zero disables a cap.
+// The caps of a facility are stored with the facility id.
+type PriceCaps struct {
+	ID        uint `gorm:"primaryKey" json:"-"`
+	DailyMax  int  `gorm:"not null;default:0" json:"daily_max"`
+	WeeklyMax int  `gorm:"not null;default:0" json:"weekly_max"`
+	MinCharge int  `gorm:"not null;default:0" json:"min_charge"`
+}
+
+// Validate check the caps aren't negative, returns the ValidationError with all the invalid fields.
+func (c PriceCaps) Validate() error {
+	validationErr := &ValidationError{}
+	for _, field := range []struct {
+		name  string
+		value int
+	}{{"daily_max", c.DailyMax}, {"weekly_max", c.WeeklyMax}, {"min_charge", c.MinCharge}} {
+		if field.value < 0 {
+			validationErr.add(field.name, "can't be negative")
+		}
+	}
+	return validationErr.orNil()
+}
@@ -0,0 +1,63 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Currency is the ISO-4217 code of the currency, the prices are in its minor unit e.g. the cents of USD.
+type Currency string
+
+// DefaultCurrency is the currency of the facility without one.
+const DefaultCurrency Currency = "USD"
+
+// currencyMinorUnits are the digits of the minor unit of the ISO-4217 currencies which don't have 2 digits, e.g. the yen has no minor unit.
+var currencyMinorUnits = map[Currency]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0, "PYG": 0, "RWF": 0, "UGX": 0, "UYI": 0,
+	"VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	"CLF": 4, "UYW": 4,
+}
+
+// ParseCurrency parse the ISO-4217 code regardless of case into Currency, the code is any 3 letters.
+func ParseCurrency(code string) (Currency, error) {
+	currency := Currency(strings.ToUpper(strings.TrimSpace(code)))
+	if len(currency) != 3 || strings.Trim(string(currency), "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+		return "", fmt.Errorf("'%s' isn't an ISO-4217 currency", code)
+	}
+	return currency, nil
+}
+
+// MinorUnits returns the digits of the minor unit of the currency, e.g. 2 for the cents of USD, the currencies are in cents by default.
+func (c Currency) MinorUnits() int {
+	if digits, isKnown := currencyMinorUnits[c]; isKnown {
+		return digits
+	}
+	return 2
+}
+
+// OrDefault returns the currency, or the default currency if empty e.g. of the amounts stored before their currency.
+func (c Currency) OrDefault() Currency {
+	if c == "" {
+		return DefaultCurrency
+	}
+	return c
+}
+
+// validateAmountCurrency parse the optional currency of the amount input, the amount is in the default currency if missing.
+func validateAmountCurrency(code string, validationErr *ValidationError) Currency {
+	if code == "" {
+		return DefaultCurrency
+	}
+	currency, err := ParseCurrency(code)
+	if err != nil {
+		validationErr.add("currency", err.Error())
+	}
+	return currency
+}
+
+// Money is the amount in the minor unit of the currency.
+type Money struct {
+	Amount   int      `json:"amount"`
+	Currency Currency `json:"currency"`
+}
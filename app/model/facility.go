@@ -0,0 +1,162 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DefaultFacilityID is the facility of the legacy single facility rates, it's the facility of the routes without one.
+const DefaultFacilityID = 1
+
+// Facility struct for storing the parking facility in DB, the rates & caps belong to a facility.
+// The tax rules of the jurisdiction (e.g. the city) are charged at the facility, the prices are in the facility currency.
+// The id is AUTOINCREMENT so that the id of a deleted facility isn't given to a new one.
+type Facility struct {
+	ID           uint     `gorm:"primaryKey;type:integer PRIMARY KEY AUTOINCREMENT" json:"id"`
+	Name         string   `gorm:"not null" json:"name"`
+	Address      string   `gorm:"not null;default:''" json:"address"`
+	Tz           string   `gorm:"not null" json:"tz"`
+	Capacity     int      `gorm:"not null;default:0" json:"capacity"`
+	Jurisdiction string   `gorm:"not null;default:''" json:"jurisdiction,omitempty"`
+	Currency     Currency `gorm:"not null;default:'USD'" json:"currency"`
+}
+
+// FacilityInput is the wire format of the facility, with its rates in the seed file.
+type FacilityInput struct {
+	ID       uint        `json:"id,omitempty"`
+	Name     string      `json:"name"`
+	Address  string      `json:"address"`
+	Tz       string      `json:"tz"`
+	Capacity *int        `json:"capacity"`
+	Rates    []RateInput `json:"rates,omitempty"`
+
+	Jurisdiction string `json:"jurisdiction,omitempty"`
+	Currency     string `json:"currency,omitempty"`
+}
+
+// SeedInput is the wire format of the seed file, either the facility list or the legacy rate list of the default facility.
+type SeedInput struct {
+	Facilities []FacilityInput `json:"facilities"`
+	Rates      []RateInput     `json:"rates"`
+}
+
+// Validate check every field of the facility input and returns the facility, or the ValidationError with all the invalid fields.
+// The rates aren't part of the facility, they are validated apart. The currency is USD by default.
+func (in FacilityInput) Validate() (Facility, error) {
+	validationErr := &ValidationError{}
+	facility := Facility{ID: in.ID, Name: strings.TrimSpace(in.Name), Address: strings.TrimSpace(in.Address), Tz: in.Tz,
+		Jurisdiction: strings.TrimSpace(in.Jurisdiction)}
+
+	if facility.Name == "" {
+		validationErr.add("name", "is required")
+	}
+
+	if in.Tz == "" {
+		validationErr.add("tz", "is required")
+	} else if _, err := time.LoadLocation(in.Tz); err != nil || in.Tz == "Local" {
+		validationErr.add("tz", fmt.Sprintf("'%s' isn't an IANA time zone", in.Tz))
+	}
+
+	if in.Capacity == nil {
+		validationErr.add("capacity", "is required")
+	} else if *in.Capacity < 0 {
+		validationErr.add("capacity", "can't be negative")
+	} else {
+		facility.Capacity = *in.Capacity
+	}
+
+	facility.Currency = DefaultCurrency
+	if in.Currency != "" {
+		currency, err := ParseCurrency(in.Currency)
+		if err != nil {
+			validationErr.add("currency", err.Error())
+		}
+		facility.Currency = currency
+	}
+
+	if err := validationErr.orNil(); err != nil {
+		return Facility{}, err
+	}
+	return facility, nil
+}
+
+// Seed is the validated seed file, the rates of every facility.
+type Seed struct {
+	Facilities []Facility
+	Rates      [][]Rate
+}
+
+// ParseSeed reads & validates the seed file. The legacy rate list is seeded as the default facility, in the tz of its first rate.
+// The facility ids are optional but unique.
+// Field names of the errors are prefixed with the facility index e.g. "facilities[1].rates[0].tz", the legacy rate list keeps "rates[0].tz".
+func ParseSeed(data []byte) (*Seed, error) {
+	var seedInput SeedInput
+	if err := json.Unmarshal(data, &seedInput); err != nil {
+		return nil, err
+	}
+
+	if seedInput.Facilities == nil {
+		rates, err := RatesInput{Rates: seedInput.Rates}.Validate()
+		if err != nil {
+			return nil, err
+		}
+		if err := CheckOverlaps(rates); err != nil {
+			return nil, err
+		}
+		facility := Facility{ID: DefaultFacilityID, Name: "Default", Tz: "UTC", Currency: DefaultCurrency}
+		if len(rates) > 0 {
+			facility.Tz = rates[0].Tz
+		}
+		return &Seed{Facilities: []Facility{facility}, Rates: [][]Rate{rates}}, nil
+	}
+
+	seed := &Seed{}
+	validationErr := &ValidationError{}
+	// the facilities sharing an id would share the rates as well, the first one is seeded only
+	seen := map[uint]int{}
+	for i, facilityInput := range seedInput.Facilities {
+		prefix := fmt.Sprintf("facilities[%d]", i)
+		facility, err := facilityInput.Validate()
+		if err != nil {
+			validationErr.addAll(prefix, err.(*ValidationError))
+		}
+		if facilityInput.ID != 0 {
+			if other, isSeen := seen[facilityInput.ID]; isSeen {
+				validationErr.add(prefix+".id", fmt.Sprintf("'%d' is the id of facilities[%d] already", facilityInput.ID, other))
+			} else {
+				seen[facilityInput.ID] = i
+			}
+		}
+
+		rates, ratesErr := RatesInput{Rates: facilityInput.Rates}.withTz(facilityInput.Tz).Validate()
+		if ratesErr != nil {
+			validationErr.addAll(prefix, ratesErr.(*ValidationError))
+			continue
+		}
+		if err := CheckOverlaps(rates); err != nil {
+			validationErr.add(prefix+".rates", err.Error())
+			continue
+		}
+		seed.Facilities = append(seed.Facilities, facility)
+		seed.Rates = append(seed.Rates, rates)
+	}
+
+	if err := validationErr.orNil(); err != nil {
+		return nil, err
+	}
+	return seed, nil
+}
+
+// withTz returns the rate list with the tz defaulting to the given tz, e.g. the tz of the facility.
+func (in RatesInput) withTz(tz string) RatesInput {
+	rates := make([]RateInput, len(in.Rates))
+	for i, rateInput := range in.Rates {
+		if rateInput.Tz == "" {
+			rateInput.Tz = tz
+		}
+		rates[i] = rateInput
+	}
+	return RatesInput{Rates: rates}
+}
@@ -0,0 +1,76 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// RateKind decides how the price of the rate is charged for the stay.
+type RateKind string
+
+const (
+	// KindFlat charges the price once for the stay in the window (default).
+	KindFlat RateKind = "flat"
+	// KindIncrement charges the price for every increment of the stay in the window.
+	KindIncrement RateKind = "increment"
+	// KindTiered charges the tier prices for every increment of the stay in the tier duration, and the price after the last tier.
+	KindTiered RateKind = "tiered"
+)
+
+// Rounding decides how the part of an increment is billed.
+type Rounding string
+
+const (
+	// RoundUp bills the part of an increment as a whole increment (default).
+	RoundUp Rounding = "up"
+	// RoundDown doesn't bill the part of an increment.
+	RoundDown Rounding = "down"
+	// RoundNearest bills the part of an increment as a whole increment if it's at least half of it.
+	RoundNearest Rounding = "nearest"
+)
+
+// Tier is the price for every increment within the first minutes of the stay, tiers follow each other.
+type Tier struct {
+	Minutes int `json:"minutes"`
+	Price   int `json:"price"`
+}
+
+// Tiers is the list of tiers of the rate, stored as json in the DB.
+type Tiers []Tier
+
+// Value writes the tiers as json into the DB.
+func (t Tiers) Value() (driver.Value, error) {
+	if len(t) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(t)
+	return string(data), err
+}
+
+// Scan reads the tiers from the json stored in the DB.
+func (t *Tiers) Scan(value interface{}) error {
+	switch data := value.(type) {
+	case nil:
+		*t = nil
+		return nil
+	case string:
+		return json.Unmarshal([]byte(data), t)
+	case []byte:
+		return json.Unmarshal(data, t)
+	}
+	return fmt.Errorf("can't scan %T into tiers", value)
+}
+
+// Increments returns the number of billed increments in the duration, as per the rounding.
+func (r Rounding) Increments(duration, increment time.Duration) int {
+	whole, part := int(duration/increment), duration%increment
+	switch {
+	case part == 0 || r == RoundDown:
+		return whole
+	case r == RoundNearest && part*2 < increment:
+		return whole
+	}
+	return whole + 1
+}
@@ -0,0 +1,51 @@
+package model
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// legacyRate is the rate schema keyed on the days, times & tz strings, before the weekday bitmask & time of day columns.
+type legacyRate struct {
+	Days  string
+	Times string
+	Tz    string
+	Price int
+}
+
+// TableName returns the table of the legacy rates.
+func (legacyRate) TableName() string {
+	return "rates"
+}
+
+// migrateLegacyRates move the legacy rates into the structured rate table within a transaction.
+func migrateLegacyRates(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var legacyRates []legacyRate
+		if err := tx.Find(&legacyRates).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Migrator().RenameTable("rates", "legacy_rates"); err != nil {
+			return err
+		}
+
+		if err := tx.AutoMigrate(&Rate{}); err != nil {
+			return err
+		}
+
+		for _, legacy := range legacyRates {
+			rate, err := NewRate(legacy.Days, legacy.Times, legacy.Tz, legacy.Price)
+			if err != nil {
+				return fmt.Errorf("could not migrate rate (%s %s %s): %w", legacy.Days, legacy.Times, legacy.Tz, err)
+			}
+
+			if err := tx.Create(&rate).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Migrator().DropTable("legacy_rates")
+	})
+}
@@ -1,18 +1,31 @@
 package model
 
 import (
-	"encoding/json"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 	"io/ioutil"
+	"time"
 )
 
-// Rate struct for storing the rate properties in DB
+// Rate struct for storing the rate properties in DB, (facility, days, start time, end time, tz, effective from) is unique.
+// The rates of the same days, start time, end time & tz are the versions of the rate window, each in force from its
+// effective from (always if nil) until the next version, or until its effective to (always if nil).
 type Rate struct {
-	Days string `gorm:"primaryKey" json:"days"`
-	Times string `gorm:"primaryKey" json:"times"`
-	Tz    string `gorm:"primaryKey" json:"tz"`
-	Price int    `json:"price"`
+	ID            uint       `gorm:"primaryKey"`
+	FacilityID    uint       `gorm:"not null;default:1;uniqueIndex:idx_rate_facility_version"`
+	Days          Weekdays   `gorm:"not null;uniqueIndex:idx_rate_facility_version"`
+	StartTime     TimeOfDay  `gorm:"not null;uniqueIndex:idx_rate_facility_version"`
+	EndTime       TimeOfDay  `gorm:"not null;uniqueIndex:idx_rate_facility_version"`
+	Tz            string     `gorm:"not null;uniqueIndex:idx_rate_facility_version"`
+	Price         int        `gorm:"not null"`
+	Priority      int        `gorm:"not null;default:0"`
+	Kind          RateKind   `gorm:"not null;default:flat"`
+	Increment     int        `gorm:"not null;default:0"`
+	Rounding      Rounding   `gorm:"not null;default:up"`
+	Tiers         Tiers      `gorm:"type:text"`
+	EffectiveFrom *time.Time `gorm:"uniqueIndex:idx_rate_facility_version"`
+	EffectiveTo   *time.Time
+	Vehicles      VehicleMultipliers `gorm:"type:text"`
 }
 
 // Rates struct contains the list of rate.
@@ -20,33 +33,73 @@ type Rates struct {
 	Rates []Rate `json:"rates"`
 }
 
-// DBMigrate migrate the DB on app start and registering the models(rate)
+// DBMigrate migrate the DB on app start and registering the models(facility, rate, price caps, calendar override, rate audit, reservation, promo code, product, surge, tax rule)
 func DBMigrate(db *gorm.DB) error {
-	return db.AutoMigrate(&Rate{})
+	if db.Migrator().HasColumn(&legacyRate{}, "times") {
+		if err := migrateLegacyRates(db); err != nil {
+			return err
+		}
+	}
+	// the rate window & version indexes are replaced by the rate facility version index, having the facility & effective from as well
+	for _, index := range []string{"idx_rate_window", "idx_rate_version"} {
+		if db.Migrator().HasIndex(&Rate{}, index) {
+			if err := db.Migrator().DropIndex(&Rate{}, index); err != nil {
+				return err
+			}
+		}
+	}
+	if err := db.AutoMigrate(&Facility{}, &Rate{}, &PriceCaps{}, &CalendarOverride{}, &RateAudit{}, &Reservation{}, &PromoCode{}, &Product{}, &Surge{}, &TaxRule{}); err != nil {
+		return err
+	}
+
+	// the rates stored before the facilities belong to the default facility, in the tz of its first rate
+	var rates []Rate
+	if err := db.Where("facility_id = ?", DefaultFacilityID).Limit(1).Find(&rates).Error; err != nil || len(rates) == 0 {
+		return err
+	}
+	defaultFacility := Facility{ID: DefaultFacilityID, Name: "Default", Tz: rates[0].Tz, Currency: DefaultCurrency}
+	return db.Clauses(clause.OnConflict{DoNothing: true}).Create(&defaultFacility).Error
 }
 
-// LoadRatesOnStart save the provided rate data in DB if not already present
+// LoadRatesOnStart save the provided facilities & rates data in DB if not already present,
+// the file is either the facility list or the legacy rate list of the default facility.
 func LoadRatesOnStart(ratesFile string, db *gorm.DB) error {
 	var obRates []Rate
 	db.Find(&obRates)
 	if len(obRates) == 0{
 		file, fileErr := ioutil.ReadFile(ratesFile)
 
-		// if error in reading the rate list json file, return error
+		// if error in reading the seed json file, return error
 		if fileErr != nil{
 			return fileErr
 		}
 
-		var rates Rates
-		ratesLoadErr := json.Unmarshal([]byte(file), &rates)
-
-		// if error in unmarshalling rate list json, return error
-		if ratesLoadErr != nil{
-			return ratesLoadErr
+		// if error in unmarshalling the seed json, any rate of the list is invalid or any rates overlap with the same priority, return the error
+		seed, seedErr := ParseSeed(file)
+		if seedErr != nil {
+			return seedErr
 		}
 
-		// saving initial rate list in the DB
-		return db.Clauses(clause.OnConflict{DoNothing: true}).Create(&rates.Rates).Error
+		// saving initial facilities & rate lists in the DB
+		return db.Transaction(func(tx *gorm.DB) error {
+			for i := range seed.Facilities {
+				if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&seed.Facilities[i]).Error; err != nil {
+					return err
+				}
+
+				rates := seed.Rates[i]
+				if len(rates) == 0 {
+					continue
+				}
+				for j := range rates {
+					rates[j].FacilityID = seed.Facilities[i].ID
+				}
+				if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&rates).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
 	}
 	return nil
-}
\ No newline at end of file
+}
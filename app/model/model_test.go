@@ -2,13 +2,17 @@ package model
 
 import (
 	"database/sql"
+	"encoding/json"
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	"path/filepath"
 	"regexp"
 	"testing"
+	"time"
 )
 
 // Suite has gorm DB, sql DB  and db instances
@@ -26,12 +30,9 @@ func (s *Suite) SetupSuite() {
 	s.DB = DB
 	s.sqlDB = sqlDB
 
-	s.rate = Rate{
-		Days: "mon,tues,thurs",
-		Times: "0900-2100",
-		Tz:  "America/Chicago",
-		Price: 1500,
-	}
+	rate, err := NewRate("mon,tues,thurs", "0900-2100", "America/Chicago", 1500)
+	require.NoError(s.T(), err)
+	s.rate = rate
 }
 
 func (s *Suite) AfterTest(_, _ string) {
@@ -43,15 +44,64 @@ func TestInit(t *testing.T) {
 }
 
 func (s *Suite) TestDBMigrate(){
+	s.mock.ExpectExec("CREATE TABLE `facilities`(.*)").WillReturnResult(sqlmock.NewResult(0, 1))
 	s.mock.ExpectExec("CREATE TABLE `rates`(.*)").WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectExec("CREATE UNIQUE INDEX `idx_rate_facility_version`(.*)").WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectExec("CREATE TABLE `price_caps`(.*)").WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectExec("CREATE TABLE `calendar_overrides`(.*)").WillReturnResult(sqlmock.NewResult(0, 1))
+	for i := 0; i < 3; i++ {
+		s.mock.ExpectExec("CREATE INDEX `idx_calendar_overrides_(facility_id|start_date|end_date)`(.*)").WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	s.mock.ExpectExec("CREATE TABLE `rate_audits`(.*)").WillReturnResult(sqlmock.NewResult(0, 1))
+	for i := 0; i < 4; i++ {
+		s.mock.ExpectExec("CREATE INDEX `idx_rate_audit(.*)").WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	s.mock.ExpectExec("CREATE TABLE `reservations`(.*)").WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectExec("CREATE INDEX `idx_reservation_interval`(.*)").WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectExec("CREATE TABLE `promo_codes`(.*)").WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectExec("CREATE UNIQUE INDEX `idx_promo_codes_code`(.*)").WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectExec("CREATE TABLE `products`(.*)").WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectExec("CREATE INDEX `idx_products_facility_id`(.*)").WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectExec("CREATE TABLE `surges`(.*)").WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectExec("CREATE TABLE `tax_rules`(.*)").WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectExec("CREATE INDEX `idx_tax_rules_(facility_id|jurisdiction)`(.*)").WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectExec("CREATE INDEX `idx_tax_rules_(facility_id|jurisdiction)`(.*)").WillReturnResult(sqlmock.NewResult(0, 1))
+	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `rates` WHERE facility_id = ? LIMIT 1")).
+		WithArgs(DefaultFacilityID).
+		WillReturnRows(s.mock.NewRows([]string{"id"}))
 	dbMigrateError := DBMigrate(s.DB)
 	require.NoError(s.T(), dbMigrateError)
 }
 
+// TestDBMigrateLegacyRates test the legacy rates table keyed on days, times & tz strings is moved into structured rates.
+func (s *Suite) TestDBMigrateLegacyRates(){
+	db, err := gorm.Open(sqlite.Open(filepath.Join(s.T().TempDir(), "rates.db")), &gorm.Config{})
+	require.NoError(s.T(), err)
+	require.NoError(s.T(), db.Exec("CREATE TABLE `rates` (`days` text,`times` text,`tz` text,`price` integer,PRIMARY KEY (`days`,`times`,`tz`))").Error)
+	require.NoError(s.T(), db.Exec("INSERT INTO `rates` VALUES ('mon,tues,thurs','0900-2100','America/Chicago',1500)").Error)
+
+	require.NoError(s.T(), DBMigrate(db))
+	require.NoError(s.T(), DBMigrate(db))
+
+	var rates []Rate
+	require.NoError(s.T(), db.Find(&rates).Error)
+	expected := s.rate
+	expected.ID, expected.FacilityID = 1, DefaultFacilityID
+	assert.Equal(s.T(), []Rate{expected}, rates)
+	assert.False(s.T(), db.Migrator().HasTable("legacy_rates"))
+
+	var facilities []Facility
+	require.NoError(s.T(), db.Find(&facilities).Error)
+	assert.Equal(s.T(), []Facility{{ID: DefaultFacilityID, Name: "Default", Tz: "America/Chicago", Currency: "USD"}}, facilities)
+}
+
 func (s *Suite) TestLoadRatesOnStart() {
 	s.mock.ExpectBegin()
+	s.mock.ExpectExec("INSERT INTO `facilities`(.*)").
+		WithArgs("Default", "", "America/Chicago", 0, "", "USD", DefaultFacilityID).
+		WillReturnResult(sqlmock.NewResult(1, 1))
 	s.mock.ExpectExec("INSERT INTO `rates`(.*)").
-		WithArgs(s.rate.Days, s.rate.Times, s.rate.Tz, s.rate.Price).
+		WithArgs(DefaultFacilityID, s.rate.Days, s.rate.StartTime, s.rate.EndTime, s.rate.Tz, s.rate.Price, s.rate.Priority, KindFlat, 0, RoundUp, nil, nil, nil, nil).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 	s.mock.ExpectCommit()
 	loadError := LoadRatesOnStart("mock_rate.json", s.DB)
@@ -60,13 +110,384 @@ func (s *Suite) TestLoadRatesOnStart() {
 
 // TestLoadRatesOnStartWithLoadedData: test the LoadRatesOnStart with already loaded data.
 func (s *Suite) TestLoadRatesOnStartWithLoadedData() {
-	rows := s.mock.NewRows([]string{"days", "times", "tz", "price"}).AddRow(s.rate.Days, s.rate.Times, s.rate.Tz, s.rate.Price)
+	rows := s.mock.NewRows([]string{"id", "days", "start_time", "end_time", "tz", "price"}).
+		AddRow(1, int(s.rate.Days), int(s.rate.StartTime), int(s.rate.EndTime), s.rate.Tz, s.rate.Price)
 	s.mock.ExpectQuery(regexp.QuoteMeta("SELECT * FROM `rates`")).WillReturnRows(rows)
 
 	loadError := LoadRatesOnStart("mock_rate.json", s.DB)
 	require.NoError(s.T(), loadError)
 }
 
+// TestStandardWindow test the start and end time as per correct format.
+func (s *Suite) TestStandardWindow(){
+	window := s.rate.Window()
+	assert.Equal(s.T(), window.Start, TimeOfDay(9*60))
+	assert.Equal(s.T(), window.End, TimeOfDay(21*60))
+}
+
+// TestMinuteWindow test the minute precision of the start and end time.
+func (s *Suite) TestMinuteWindow(){
+	window, err := ParseTimeWindow("0630-1815")
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), window.Start.Hour(), 6)
+	assert.Equal(s.T(), window.Start.Minute(), 30)
+	assert.Equal(s.T(), window.End.Hour(), 18)
+	assert.Equal(s.T(), window.End.Minute(), 15)
+	assert.Equal(s.T(), window.String(), "0630-1815")
+}
+
+// TestMidnightWindow test the start and end of the day.
+func (s *Suite) TestMidnightWindow(){
+	window, err := ParseTimeWindow("0000-2400")
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), window.Start, TimeOfDay(0))
+	assert.Equal(s.T(), window.End, TimeOfDay(MinutesPerDay))
+
+	window, err = ParseTimeWindow("1000-2000")
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), window.Start, TimeOfDay(10*60))
+}
+
+// TestNotStandardWindow should throw error for incorrect format.
+func (s *Suite) TestNotStandardWindow(){
+	_, err := ParseTimeWindow("09002100")
+	assert.Error(s.T(), err)
+	assert.Equal(s.T(), err.Error(), "time value is not as per the standard")
+}
+
+// TestNonParsableStartWindow should throw error for non-parsable start time.
+func (s *Suite) TestNonParsableStartWindow(){
+	_, err := ParseTimeWindow("0a00-2100")
+	assert.Error(s.T(), err)
+	assert.Equal(s.T(), err.Error(), "time of day '0a00' isn't as per HHMM format")
+}
+
+// TestNonParsableEndWindow should throw error for non-parsable end time.
+func (s *Suite) TestNonParsableEndWindow(){
+	_, err := ParseTimeWindow("0900-2Z00")
+	assert.Error(s.T(), err)
+	assert.Equal(s.T(), err.Error(), "time of day '2Z00' isn't as per HHMM format")
+}
+
+// TestOutOfRangeWindow should throw error for the time past the end of day or minute past 59.
+func (s *Suite) TestOutOfRangeWindow(){
+	for _, times := range []string{"0900-2401", "0960-2100", "900-2100"} {
+		_, err := ParseTimeWindow(times)
+		assert.Error(s.T(), err)
+	}
+}
+
+// TestParseWeekdays test the weekday names & their aliases.
+func (s *Suite) TestParseWeekdays(){
+	weekdays, err := ParseWeekdays("Sun, tue,Thursday")
+	assert.NoError(s.T(), err)
+	assert.True(s.T(), weekdays.Has(time.Sunday))
+	assert.True(s.T(), weekdays.Has(time.Tuesday))
+	assert.True(s.T(), weekdays.Has(time.Thursday))
+	assert.False(s.T(), weekdays.Has(time.Monday))
+	assert.Equal(s.T(), weekdays.String(), "tues,thurs,sun")
+
+	_, err = ParseWeekdays("mon,moonday")
+	assert.Error(s.T(), err)
+	assert.Equal(s.T(), err.Error(), "unknown day 'moonday'")
+}
+
+// TestRateJSON test the rate is written & read in the days, times, tz & price wire format.
+func (s *Suite) TestRateJSON(){
+	jsonRate, err := json.Marshal(s.rate)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), string(jsonRate), `{"days":"mon,tues,thurs","times":"0900-2100","tz":"America/Chicago","price":1500}`)
+
+	var rate Rate
+	assert.NoError(s.T(), json.Unmarshal(jsonRate, &rate))
+	assert.Equal(s.T(), rate, s.rate)
+
+	assert.Error(s.T(), json.Unmarshal([]byte(`{"days":"mon","times":"9-21","tz":"America/Chicago","price":1}`), &rate))
+}
+
+// TestValidateRateInput should return every invalid field of the rate.
+func (s *Suite) TestValidateRateInput(){
+	price := -1
+	_, err := RateInput{Days: "mon,funday", Times: "2100-0900", Tz: "America/Springfield", Price: &price}.Validate()
+	require.Error(s.T(), err)
+	assert.Equal(s.T(), err.(*ValidationError).Fields, []FieldError{
+		{Field: "days", Message: "unknown day 'funday'"},
+		{Field: "times", Message: "end '0900' isn't after start '2100'"},
+		{Field: "tz", Message: "'America/Springfield' isn't an IANA time zone"},
+		{Field: "price", Message: "can't be negative"},
+	})
+
+	_, err = RateInput{}.Validate()
+	require.Error(s.T(), err)
+	assert.Equal(s.T(), err.Error(), "validation failed, days: is required; times: is required; tz: is required; price: is required")
+}
+
+// TestRateKindJSON test the tiered rate is written & read with its kind, increment, rounding & tiers.
+func (s *Suite) TestRateKindJSON(){
+	data := `{"days":"mon","times":"0900-2100","tz":"America/Chicago","price":300,"kind":"tiered","increment":60,"rounding":"nearest","tiers":[{"minutes":60,"price":500}]}`
+	var rate Rate
+	require.NoError(s.T(), json.Unmarshal([]byte(data), &rate))
+	assert.Equal(s.T(), KindTiered, rate.Kind)
+	assert.Equal(s.T(), RoundNearest, rate.Rounding)
+	assert.Equal(s.T(), Tiers{{Minutes: 60, Price: 500}}, rate.Tiers)
+
+	jsonRate, err := json.Marshal(rate)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), data, string(jsonRate))
+
+	stored, err := rate.Tiers.Value()
+	assert.NoError(s.T(), err)
+	var tiers Tiers
+	assert.NoError(s.T(), tiers.Scan([]byte(stored.(string))))
+	assert.Equal(s.T(), rate.Tiers, tiers)
+}
+
+// TestRateVehicles test the vehicle multipliers of the rate wire format and the rate prices for the vehicle class.
+func (s *Suite) TestRateVehicles(){
+	data := `{"days":"mon","times":"0900-2100","tz":"America/Chicago","price":1500,"vehicles":{"motorcycle":0.5,"oversize":1.5}}`
+	var rate Rate
+	require.NoError(s.T(), json.Unmarshal([]byte(data), &rate))
+	assert.Equal(s.T(), VehicleMultipliers{VehicleOversize: 1.5, VehicleMotorcycle: 0.5}, rate.Vehicles)
+
+	jsonRate, err := json.Marshal(rate)
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), data, string(jsonRate))
+
+	assert.Equal(s.T(), 2250, rate.ForVehicle(VehicleOversize).Price)
+	assert.Equal(s.T(), 750, rate.ForVehicle(VehicleMotorcycle).Price)
+	assert.Equal(s.T(), 1500, rate.ForVehicle(VehicleCompact).Price)
+
+	price := 100
+	_, err = RateInput{Days: "mon", Times: "0900-2100", Tz: "America/Chicago", Price: &price,
+		Vehicles: VehicleMultipliers{"truck": 2, VehicleOversize: 0}}.Validate()
+	require.Error(s.T(), err)
+	assert.Equal(s.T(), "validation failed, vehicles.oversize: multiplier must be positive; "+
+		"vehicles.truck: unknown vehicle 'truck', isn't compact, standard, oversize or motorcycle", err.Error())
+
+	_, err = ParseVehicleClass("bus")
+	assert.Error(s.T(), err)
+	class, err := ParseVehicleClass("")
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), VehicleStandard, class)
+}
+
+// TestValidateRateKind should return the invalid kind fields of the rate.
+func (s *Suite) TestValidateRateKind(){
+	price := 100
+	_, err := RateInput{Days: "mon", Times: "0900-2100", Tz: "America/Chicago", Price: &price, Increment: 15}.Validate()
+	require.Error(s.T(), err)
+	assert.Equal(s.T(), "validation failed, increment: isn't allowed for the flat rate", err.Error())
+
+	_, err = RateInput{Days: "mon", Times: "0900-2100", Tz: "America/Chicago", Price: &price, Kind: "hourly"}.Validate()
+	require.Error(s.T(), err)
+	assert.Equal(s.T(), "validation failed, kind: unknown kind 'hourly', isn't flat, increment or tiered", err.Error())
+
+	_, err = RateInput{Days: "mon", Times: "0900-2100", Tz: "America/Chicago", Price: &price, Kind: "increment", Rounding: "half"}.Validate()
+	require.Error(s.T(), err)
+	assert.Equal(s.T(), "validation failed, increment: must be 1 to 1440 minutes; rounding: unknown rounding 'half', isn't up, down or nearest", err.Error())
+
+	_, err = RateInput{Days: "mon", Times: "0900-2100", Tz: "America/Chicago", Price: &price, Kind: "tiered", Increment: 60, Tiers: Tiers{{Minutes: 90, Price: -1}}}.Validate()
+	require.Error(s.T(), err)
+	assert.Equal(s.T(), "validation failed, tiers[0].minutes: must be a positive multiple of the increment; tiers[0].price: can't be negative", err.Error())
+
+	rate, err := RateInput{Days: "mon", Times: "0900-2100", Tz: "America/Chicago", Price: &price, Kind: "increment", Increment: 15}.Validate()
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), RoundUp, rate.Rounding)
+}
+
+// TestRoundingIncrements test the billed increments of the duration for every rounding.
+func (s *Suite) TestRoundingIncrements(){
+	assert.Equal(s.T(), 2, RoundUp.Increments(61*time.Minute, time.Hour))
+	assert.Equal(s.T(), 1, RoundDown.Increments(119*time.Minute, time.Hour))
+	assert.Equal(s.T(), 1, RoundNearest.Increments(89*time.Minute, time.Hour))
+	assert.Equal(s.T(), 2, RoundNearest.Increments(90*time.Minute, time.Hour))
+	assert.Equal(s.T(), 4, RoundUp.Increments(time.Hour, 15*time.Minute))
+}
+
+// TestValidateCalendarOverride should return the override with the default end date & rate days, or every invalid field.
+func (s *Suite) TestValidateCalendarOverride(){
+	price := 4000
+	override, err := CalendarOverrideInput{Name: "Stadium", StartDate: "2015-07-10", Rates: []RateInput{{Times: "1700-2400", Tz: "America/Chicago", Price: &price}}}.Validate()
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), "2015-07-10", override.EndDate)
+	assert.Equal(s.T(), AllWeekdays, override.Rates[0].Days)
+
+	multiplier := 0.0
+	_, err = CalendarOverrideInput{StartDate: "2015-07-10", EndDate: "2015-07-09", Multiplier: &multiplier}.Validate()
+	require.Error(s.T(), err)
+	assert.Equal(s.T(), "validation failed, name: is required; end_date: '2015-07-09' is before start date '2015-07-10'; multiplier: must be positive", err.Error())
+
+	_, err = CalendarInput{Overrides: []CalendarOverrideInput{{Name: "July 4th", StartDate: "2015-07-04"}}}.Validate()
+	require.Error(s.T(), err)
+	assert.Equal(s.T(), "validation failed, overrides[0].multiplier: either multiplier or rates is required", err.Error())
+}
+
+// TestCalendarOverride test the override dates & the multiplied rate prices.
+func (s *Suite) TestCalendarOverride(){
+	override := CalendarOverride{Name: "July 4th", StartDate: "2015-07-03", EndDate: "2015-07-05", Multiplier: 1.25}
+	assert.True(s.T(), override.Covers(time.Date(2015, time.July, 5, 23, 0, 0, 0, time.UTC)))
+	assert.False(s.T(), override.Covers(time.Date(2015, time.July, 6, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(s.T(), 1875, override.Apply(s.rate).Price)
+
+	other := CalendarOverride{Name: "Weekend", StartDate: "2015-07-05", EndDate: "2015-07-06", Multiplier: 2}
+	assert.Error(s.T(), CheckCalendarOverlaps([]CalendarOverride{override, other}))
+	other.StartDate = "2015-07-06"
+	assert.NoError(s.T(), CheckCalendarOverlaps([]CalendarOverride{override, other}))
+}
+
+// TestRateVersions test the versions of the rate window don't overlap, while the effective rates of other windows do.
+func (s *Suite) TestRateVersions(){
+	from := time.Date(2015, time.August, 1, 0, 0, 0, 0, time.UTC)
+	version := s.rate
+	version.EffectiveFrom = &from
+	assert.False(s.T(), version.SameKey(s.rate))
+	assert.True(s.T(), version.SameWindow(s.rate))
+	assert.NoError(s.T(), CheckOverlap(version, []Rate{s.rate}))
+	assert.True(s.T(), version.InForce(from))
+	assert.False(s.T(), version.InForce(from.Add(-time.Second)))
+
+	// the overlapping rate expiring before the version takes effect
+	overlapping, err := NewRate("thurs", "2000-2300", "America/Chicago", 500)
+	require.NoError(s.T(), err)
+	overlapping.EffectiveTo = &from
+	assert.NoError(s.T(), CheckOverlap(version, []Rate{overlapping}))
+	assert.Error(s.T(), CheckOverlap(s.rate, []Rate{overlapping}))
+
+	price := 100
+	_, err = RateInput{Days: "mon", Times: "0900-2100", Tz: "America/Chicago", Price: &price, EffectiveFrom: &from, EffectiveTo: &from}.Validate()
+	require.Error(s.T(), err)
+	assert.Equal(s.T(), "validation failed, effective_to: '2015-08-01T00:00:00Z' isn't after effective from '2015-08-01T00:00:00Z'", err.Error())
+}
+
+// TestRateAudit test the rate changes are recorded with the before & after rates, and the entries can't be changed.
+func (s *Suite) TestRateAudit(){
+	db, err := gorm.Open(sqlite.Open(filepath.Join(s.T().TempDir(), "rates.db")), &gorm.Config{})
+	require.NoError(s.T(), err)
+	require.NoError(s.T(), DBMigrate(db))
+
+	auditor := Auditor{Actor: "ops", RequestID: "req-1"}
+	before, after := s.rate, s.rate
+	before.ID, after.ID, after.Price = 3, 3, 1800
+	require.NoError(s.T(), auditor.Record(db, AuditUpdate, &before, &after))
+
+	var audit RateAudit
+	require.NoError(s.T(), db.First(&audit).Error)
+	data, err := json.Marshal(audit)
+	require.NoError(s.T(), err)
+	assert.JSONEq(s.T(), `{"id":1,"at":"`+audit.At.Format(time.RFC3339Nano)+`","actor":"ops","request_id":"req-1","action":"update","rate_id":3,"facility_id":1,`+
+		`"days":"mon,tues,thurs","times":"0900-2100","tz":"America/Chicago",`+
+		`"before":{"id":3,"days":"mon,tues,thurs","times":"0900-2100","tz":"America/Chicago","price":1500},`+
+		`"after":{"id":3,"days":"mon,tues,thurs","times":"0900-2100","tz":"America/Chicago","price":1800}}`, string(data))
+
+	assert.ErrorIs(s.T(), db.Model(&audit).Update("actor", "someone").Error, ErrAuditAppendOnly)
+	assert.ErrorIs(s.T(), db.Delete(&audit).Error, ErrAuditAppendOnly)
+}
+
+// TestLoadRatesOnStartInvalidRates should return the validation error with the index of the invalid rate.
+func (s *Suite) TestLoadRatesOnStartInvalidRates() {
+	loadError := LoadRatesOnStart("mock_invalid_rate.json", s.DB)
+	require.Error(s.T(), loadError)
+	fields := loadError.(*ValidationError).Fields
+	assert.Len(s.T(), fields, 4)
+	assert.Equal(s.T(), fields[0].Field, "rates[1].days")
+}
+
+// TestParseSeedFacilities test the facility list seed, the rate tz defaults to the facility tz and the errors are prefixed with the facility index.
+func (s *Suite) TestParseSeedFacilities(){
+	seed, err := ParseSeed([]byte(`{"facilities":[{"id":2,"name":"Wrigley","tz":"America/Chicago","capacity":120,` +
+		`"rates":[{"days":"mon,tues,thurs","times":"0900-2100","price":1500}]},{"id":3,"name":"Fenway","tz":"America/New_York","capacity":80}]}`))
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), []Facility{{ID: 2, Name: "Wrigley", Tz: "America/Chicago", Capacity: 120, Currency: "USD"}, {ID: 3, Name: "Fenway", Tz: "America/New_York", Capacity: 80, Currency: "USD"}}, seed.Facilities)
+	assert.Equal(s.T(), [][]Rate{{s.rate}, {}}, seed.Rates)
+
+	_, err = ParseSeed([]byte(`{"facilities":[{"name":"Wrigley","tz":"America/Chicago","capacity":120,"rates":[{"days":"mon","times":"0900-2100","price":-1}]}]}`))
+	require.Error(s.T(), err)
+	fields := err.(*ValidationError).Fields
+	require.Len(s.T(), fields, 1)
+	assert.Equal(s.T(), fields[0].Field, "facilities[0].rates[0].price")
+
+	// the second facility of the id would be seeded as the first one
+	_, err = ParseSeed([]byte(`{"facilities":[{"id":2,"name":"Wrigley","tz":"America/Chicago","capacity":120},{"id":2,"name":"Fenway","tz":"America/New_York","capacity":80}]}`))
+	require.Error(s.T(), err)
+	assert.Equal(s.T(), []FieldError{{Field: "facilities[1].id", Message: "'2' is the id of facilities[0] already"}}, err.(*ValidationError).Fields)
+}
+
+// TestValidateReservation should return every invalid field of the reservation, the times are in UTC.
+func (s *Suite) TestValidateReservation(){
+	start := time.Date(2015, time.July, 4, 10, 0, 0, 0, time.FixedZone("CDT", -5*60*60))
+	end := start.Add(-time.Hour)
+	_, err := ReservationInput{Start: &start, End: &end, Vehicle: "bus"}.Validate()
+	require.Error(s.T(), err)
+	assert.Equal(s.T(), "validation failed, end: '2015-07-04T09:00:00-05:00' isn't after start '2015-07-04T10:00:00-05:00'; "+
+		"vehicle: unknown vehicle 'bus', isn't compact, standard, oversize or motorcycle", err.Error())
+
+	end = start.Add(2 * time.Hour)
+	reservation, err := ReservationInput{Start: &start, End: &end}.Validate()
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), Reservation{Start: start.UTC(), End: end.UTC(), Vehicle: VehicleStandard}, reservation)
+}
+
+// TestPeakOccupancy test the most reservations at the same instant of the stay, back to back reservations share the space.
+func (s *Suite) TestPeakOccupancy(){
+	at := func(hour int) time.Time {
+		return time.Date(2015, time.July, 4, hour, 0, 0, 0, time.UTC)
+	}
+	reservations := []Reservation{
+		{Start: at(8), End: at(10)},
+		{Start: at(10), End: at(12)},
+		{Start: at(9), End: at(11)},
+		{Start: at(13), End: at(15)},
+	}
+	assert.Equal(s.T(), 2, PeakOccupancy(reservations, at(8), at(12)))
+	assert.Equal(s.T(), 1, PeakOccupancy(reservations, at(11), at(14)))
+	assert.Equal(s.T(), 0, PeakOccupancy(reservations, at(15), at(18)))
+}
+
+// TestCheckOverlap should reject the rate overlapping on the same tz & weekday with the same priority.
+func (s *Suite) TestCheckOverlap(){
+	overlapping, err := NewRate("thurs,fri", "2000-2300", "America/Chicago", 500)
+	require.NoError(s.T(), err)
+	overlapErr := CheckOverlap(overlapping, []Rate{s.rate})
+	require.Error(s.T(), overlapErr)
+	assert.Equal(s.T(), overlapErr.Error(), "rate (thurs,fri 2000-2300 America/Chicago) overlaps rate (mon,tues,thurs 0900-2100 America/Chicago) on thurs, with the same priority 0")
+
+	// higher priority is accepted
+	overlapping.Priority = 1
+	assert.NoError(s.T(), CheckOverlap(overlapping, []Rate{s.rate}))
+
+	// adjacent window, other weekday or tz doesn't overlap
+	adjacent, err := NewRate("thurs", "2100-2300", "America/Chicago", 500)
+	require.NoError(s.T(), err)
+	otherDay, err := NewRate("wed", "0900-2100", "America/Chicago", 500)
+	require.NoError(s.T(), err)
+	otherTz, err := NewRate("mon", "0900-2100", "America/New_York", 500)
+	require.NoError(s.T(), err)
+	assert.NoError(s.T(), CheckOverlaps([]Rate{s.rate, adjacent, otherDay, otherTz}))
+
+	// same key replaces the rate on upsert
+	sameKey := s.rate
+	sameKey.Price = 4000
+	assert.NoError(s.T(), CheckOverlap(sameKey, []Rate{s.rate}))
+
+	// stored rate can't take the key of another stored rate, even with other priority
+	stored := s.rate
+	stored.ID = 1
+	patched := s.rate
+	patched.ID, patched.Priority = 2, 1
+	assert.NoError(s.T(), CheckOverlap(stored, []Rate{stored}))
+	overlapErr = CheckOverlap(patched, []Rate{stored, patched})
+	require.Error(s.T(), overlapErr)
+	assert.Equal(s.T(), overlapErr.Error(), "rate (mon,tues,thurs 0900-2100 America/Chicago) has the same days, times & tz as rate 1")
+}
+
+// TestValidatePriceCaps should return every negative cap.
+func (s *Suite) TestValidatePriceCaps(){
+	assert.NoError(s.T(), PriceCaps{DailyMax: 3000}.Validate())
+	err := PriceCaps{DailyMax: -1, MinCharge: -1}.Validate()
+	require.Error(s.T(), err)
+	assert.Equal(s.T(), err.Error(), "validation failed, daily_max: can't be negative; min_charge: can't be negative")
+}
+
 // GetDatabase: set the sql mock and gorm v=based DB for testing.
 func GetDatabase(s *Suite) (sqlmock.Sqlmock, *gorm.DB, *sql.DB){
 	sqlDB, mock, err := sqlmock.NewWithDSN("sql_mock_db", sqlmock.QueryMatcherOption(sqlmock.QueryMatcherRegexp))
@@ -85,4 +506,124 @@ func GetDatabase(s *Suite) (sqlmock.Sqlmock, *gorm.DB, *sql.DB){
 	require.NoError(s.T(), err)
 
 	return mock, DB, sqlDB
-}
\ No newline at end of file
+}
+// TestValidatePromoCode should return every invalid field of the promo code, the code is upper case.
+func (s *Suite) TestValidatePromoCode(){
+	_, err := PromoCodeInput{Code: "two words", Kind: "percent_off", Percent: 150, Amount: 100, Currency: "EUR", MaxUses: -1}.Validate()
+	require.Error(s.T(), err)
+	assert.Equal(s.T(), "validation failed, code: 'two words' can't have spaces; percent: must be 1 to 100; "+
+		"amount: is only for the fixed_off code; currency: is only for the fixed_off code; max_uses: can't be negative", err.Error())
+
+	_, err = PromoCodeInput{Code: "SUMMER", Kind: "fixed_off", Amount: 500, Currency: "euro"}.Validate()
+	require.Error(s.T(), err)
+	assert.Equal(s.T(), "validation failed, currency: 'euro' isn't an ISO-4217 currency", err.Error())
+
+	// the amount is in USD by default
+	promo, err := PromoCodeInput{Code: " summer ", Kind: "fixed_off", Amount: 500, Facilities: FacilityIDs{2}}.Validate()
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), PromoCode{Code: "SUMMER", Kind: PromoFixedOff, Amount: 500, Currency: "USD", Facilities: FacilityIDs{2}}, promo)
+
+	promo, err = PromoCodeInput{Code: "SUMMER", Kind: "fixed_off", Amount: 500, Currency: "eur"}.Validate()
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), Currency("EUR"), promo.Currency)
+}
+
+// TestPromoCheck should return the reason the promo code can't be applied at the facility at the instant.
+func (s *Suite) TestPromoCheck(){
+	from := time.Date(2015, time.July, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+	promo := PromoCode{Code: "SUMMER", ValidFrom: &from, ValidTo: &to, MaxUses: 2, Uses: 1, Facilities: FacilityIDs{1, 2}}
+
+	assert.NoError(s.T(), promo.Check(2, from))
+	assert.EqualError(s.T(), promo.Check(2, from.Add(-time.Second)), "promo code 'SUMMER' isn't valid until 2015-07-01T00:00:00Z")
+	assert.EqualError(s.T(), promo.Check(2, to), "promo code 'SUMMER' expired at 2015-08-01T00:00:00Z")
+	assert.EqualError(s.T(), promo.Check(3, from), "promo code 'SUMMER' isn't valid at facility '3'")
+
+	promo.Uses = 2
+	assert.EqualError(s.T(), promo.Check(2, from), "promo code 'SUMMER' reached its limit of 2 uses")
+}
+
+// TestProductCovers test the stay is entered & left within the product windows of the same local day, up to the max minutes.
+func (s *Suite) TestProductCovers(){
+	price := 1200
+	product, err := ProductInput{Name: "Early bird", Days: "mon,tues,wed,thurs,fri", Entry: "0500-0900", Exit: "1500-2400", Tz: "America/Chicago", Price: &price}.Validate()
+	require.NoError(s.T(), err)
+	loc, err := time.LoadLocation("America/Chicago")
+	require.NoError(s.T(), err)
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2015, time.July, day, hour, minute, 0, 0, loc)
+	}
+
+	assert.True(s.T(), product.Covers(at(2, 9, 0), at(2, 15, 0), loc))
+	assert.True(s.T(), product.Covers(at(2, 8, 0), at(3, 0, 0), loc))
+	assert.False(s.T(), product.Covers(at(2, 9, 1), at(2, 17, 0), loc))
+	assert.False(s.T(), product.Covers(at(2, 8, 0), at(2, 14, 59), loc))
+	assert.False(s.T(), product.Covers(at(2, 8, 0), at(3, 16, 0), loc))
+	// saturday isn't a product day, the product windows are of the local time
+	assert.False(s.T(), product.Covers(at(4, 8, 0), at(4, 16, 0), loc))
+	assert.False(s.T(), product.Covers(at(2, 8, 0), at(2, 16, 0), time.UTC))
+
+	product.MaxMinutes = 8 * 60
+	assert.True(s.T(), product.Covers(at(2, 8, 0), at(2, 16, 0), loc))
+	assert.False(s.T(), product.Covers(at(2, 8, 0), at(2, 16, 1), loc))
+}
+
+// TestValidateSurge should return every invalid field of the surge settings.
+func (s *Suite) TestValidateSurge(){
+	surge := Surge{Bands: SurgeBands{{Occupancy: 50, Multiplier: 1.2}, {Occupancy: 50, Multiplier: 0}, {Occupancy: 120, Multiplier: 2}}, MinMultiplier: 1.5, MaxMultiplier: 1.2}
+	err := surge.Validate()
+	require.Error(s.T(), err)
+	assert.Equal(s.T(), "validation failed, bands[1].occupancy: '50' isn't above the previous band occupancy '50'; bands[1].multiplier: must be positive; "+
+		"bands[2].occupancy: must be 0 to 100; max_multiplier: '1.2' is below min multiplier '1.5'", err.Error())
+}
+
+// TestSurgeMultiplier test the multiplier of the highest band reached by the occupancy, within the clamps.
+func (s *Suite) TestSurgeMultiplier(){
+	surge := Surge{Bands: SurgeBands{{Occupancy: 0, Multiplier: 0.8}, {Occupancy: 25, Multiplier: 1}, {Occupancy: 80, Multiplier: 1.5}, {Occupancy: 95, Multiplier: 2.5}}}
+	assert.Equal(s.T(), 0.8, surge.Multiplier(4, 20))
+	assert.Equal(s.T(), 1.0, surge.Multiplier(5, 20))
+	assert.Equal(s.T(), 1.5, surge.Multiplier(16, 20))
+	assert.Equal(s.T(), 2.5, surge.Multiplier(20, 20))
+	// the facility without capacity isn't surged
+	assert.Equal(s.T(), 1.0, surge.Multiplier(0, 0))
+
+	surge.MinMultiplier, surge.MaxMultiplier = 0.9, 2
+	assert.Equal(s.T(), 0.9, surge.Multiplier(4, 20))
+	assert.Equal(s.T(), 2.0, surge.Multiplier(20, 20))
+}
+
+// TestTaxRuleCharge test the percent is rounded half up to the cent with integer math, the flat amount is charged as it's.
+func (s *Suite) TestTaxRuleCharge(){
+	percent := TaxRule{Kind: TaxPercent, BasisPoints: 2375}
+	assert.Equal(s.T(), 356, percent.Charge(1500))
+	assert.Equal(s.T(), 119, percent.Charge(500))
+	assert.Equal(s.T(), 0, percent.Charge(0))
+	// 10.25% of 1000 cents is 102.5, rounded up
+	assert.Equal(s.T(), 103, TaxRule{Kind: TaxPercent, BasisPoints: 1025}.Charge(1000))
+	assert.Equal(s.T(), 199, TaxRule{Kind: TaxFlat, Amount: 199}.Charge(1500))
+}
+
+// TestParseCurrency test the ISO-4217 code is parsed regardless of case, with the digits of its minor unit.
+func (s *Suite) TestParseCurrency(){
+	currency, err := ParseCurrency(" eur")
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), Currency("EUR"), currency)
+	assert.Equal(s.T(), 2, currency.MinorUnits())
+	assert.Equal(s.T(), 0, Currency("JPY").MinorUnits())
+	assert.Equal(s.T(), 3, Currency("KWD").MinorUnits())
+
+	// the currencies with cents aren't listed
+	currency, err = ParseCurrency("thb")
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 2, currency.MinorUnits())
+
+	_, err = ParseCurrency("EURO")
+	assert.EqualError(s.T(), err, "'EURO' isn't an ISO-4217 currency")
+
+	facility, err := FacilityInput{Name: "Fenway", Tz: "America/New_York", Capacity: new(int)}.Validate()
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), DefaultCurrency, facility.Currency)
+	_, err = FacilityInput{Name: "Fenway", Tz: "America/New_York", Capacity: new(int), Currency: "U$D"}.Validate()
+	require.Error(s.T(), err)
+	assert.Equal(s.T(), []FieldError{{Field: "currency", Message: "'U$D' isn't an ISO-4217 currency"}}, err.(*ValidationError).Fields)
+}
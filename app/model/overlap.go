@@ -0,0 +1,76 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// OverlapError is returned when two effective rates of the same tz cover the same weekday & time with the same priority.
+type OverlapError struct {
+	Rate  Rate
+	Other Rate
+}
+
+// Error describes the clash of the two rates.
+func (e *OverlapError) Error() string {
+	if e.Rate.SameKey(e.Other) {
+		return fmt.Sprintf("rate (%s %s %s) has the same days, times & tz as rate %d", e.Rate.Days, e.Rate.Window(), e.Rate.Tz, e.Other.ID)
+	}
+	return fmt.Sprintf("rate (%s %s %s) overlaps rate (%s %s %s) on %s, with the same priority %d",
+		e.Rate.Days, e.Rate.Window(), e.Rate.Tz, e.Other.Days, e.Other.Window(), e.Other.Tz,
+		e.Rate.Days&e.Other.Days, e.Rate.Priority)
+}
+
+// SameKey check if both rates have the same days, times, tz & effective from, i.e. one replaces the other on upsert.
+func (r Rate) SameKey(other Rate) bool {
+	return r.SameWindow(other) && sameTime(r.EffectiveFrom, other.EffectiveFrom)
+}
+
+// SameWindow check if both rates have the same days, times & tz, i.e. they are the versions of the rate window.
+func (r Rate) SameWindow(other Rate) bool {
+	return r.Days == other.Days && r.StartTime == other.StartTime && r.EndTime == other.EndTime && r.Tz == other.Tz
+}
+
+// Overlaps check if the rate windows share a moment on a weekday of the same tz, while both are effective.
+func (r Rate) Overlaps(other Rate) bool {
+	return r.Tz == other.Tz && r.Days&other.Days != 0 && r.StartTime < other.EndTime && other.StartTime < r.EndTime &&
+		(r.EffectiveFrom == nil || other.EffectiveTo == nil || r.EffectiveFrom.Before(*other.EffectiveTo)) &&
+		(other.EffectiveFrom == nil || r.EffectiveTo == nil || other.EffectiveFrom.Before(*r.EffectiveTo))
+}
+
+// sameTime check if both optional times are nil or the same instant.
+func sameTime(t, other *time.Time) bool {
+	if t == nil || other == nil {
+		return t == other
+	}
+	return t.Equal(*other)
+}
+
+// CheckOverlap returns the OverlapError if the rate overlaps any of the rates with the same priority.
+// A stored rate (non-zero id) skips itself and can't take the key of another rate,
+// a new rate skips the rate with the same key as it replaces it on upsert.
+func CheckOverlap(rate Rate, rates []Rate) error {
+	for _, other := range rates {
+		if rate.ID != 0 && rate.ID == other.ID || rate.ID == 0 && rate.SameKey(other) {
+			continue
+		}
+		// the other versions of the rate window don't overlap, the later version takes over from its effective from
+		if rate.SameWindow(other) && !rate.SameKey(other) {
+			continue
+		}
+		if rate.Overlaps(other) && (rate.Priority == other.Priority || rate.SameKey(other)) {
+			return &OverlapError{Rate: rate, Other: other}
+		}
+	}
+	return nil
+}
+
+// CheckOverlaps returns the OverlapError for the first pair of the rates overlapping with the same priority.
+func CheckOverlaps(rates []Rate) error {
+	for i, rate := range rates {
+		if err := CheckOverlap(rate, rates[i+1:]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Product struct for storing the flat price product of the facility in DB, e.g. the early-bird "enter before 9:00, leave after 15:00".
+// The stay is entered within the entry window & left within the exit window of the same local day, on the product days,
+// and lasts at most max minutes (no limit if 0). The price is charged once for the stay, multiplied for the vehicle class like the rates.
+type Product struct {
+	ID         uint               `gorm:"primaryKey"`
+	FacilityID uint               `gorm:"not null;default:1;index"`
+	Name       string             `gorm:"not null"`
+	Days       Weekdays           `gorm:"not null"`
+	EntryStart TimeOfDay          `gorm:"not null"`
+	EntryEnd   TimeOfDay          `gorm:"not null"`
+	ExitStart  TimeOfDay          `gorm:"not null"`
+	ExitEnd    TimeOfDay          `gorm:"not null"`
+	Tz         string             `gorm:"not null"`
+	Price      int                `gorm:"not null"`
+	MaxMinutes int                `gorm:"not null;default:0"`
+	Vehicles   VehicleMultipliers `gorm:"type:text"`
+}
+
+// ProductInput is the wire format of the product, the entry & exit windows are "HHMM-HHMM" like the rate times.
+type ProductInput struct {
+	ID         uint   `json:"id,omitempty"`
+	Name       string `json:"name"`
+	Days       string `json:"days"`
+	Entry      string `json:"entry"`
+	Exit       string `json:"exit"`
+	Tz         string `json:"tz"`
+	Price      *int   `json:"price"`
+	MaxMinutes int    `json:"max_minutes,omitempty"`
+
+	Vehicles VehicleMultipliers `json:"vehicles,omitempty"`
+}
+
+// Validate check every field of the product input and returns the product, or the ValidationError with all the invalid fields.
+// The exit window can't end before the entry window starts.
+func (in ProductInput) Validate() (Product, error) {
+	validationErr := &ValidationError{}
+	product := Product{Name: strings.TrimSpace(in.Name), Tz: in.Tz}
+
+	if product.Name == "" {
+		validationErr.add("name", "is required")
+	}
+
+	if strings.TrimSpace(in.Days) == "" {
+		validationErr.add("days", "is required")
+	} else if weekdays, err := ParseWeekdays(in.Days); err != nil {
+		validationErr.add("days", err.Error())
+	} else {
+		product.Days = weekdays
+	}
+
+	entry, isEntryValid := validateProductWindow("entry", in.Entry, validationErr)
+	exit, isExitValid := validateProductWindow("exit", in.Exit, validationErr)
+	if isEntryValid && isExitValid && exit.End < entry.Start {
+		validationErr.add("exit", fmt.Sprintf("'%s' ends before entry '%s' starts", exit, entry))
+	}
+	product.EntryStart, product.EntryEnd = entry.Start, entry.End
+	product.ExitStart, product.ExitEnd = exit.Start, exit.End
+
+	if in.Tz == "" {
+		validationErr.add("tz", "is required")
+	} else if _, err := time.LoadLocation(in.Tz); err != nil || in.Tz == "Local" {
+		validationErr.add("tz", fmt.Sprintf("'%s' isn't an IANA time zone", in.Tz))
+	}
+
+	if in.Price == nil {
+		validationErr.add("price", "is required")
+	} else if *in.Price < 0 {
+		validationErr.add("price", "can't be negative")
+	} else {
+		product.Price = *in.Price
+	}
+
+	if in.MaxMinutes < 0 {
+		validationErr.add("max_minutes", "can't be negative")
+	}
+	product.MaxMinutes = in.MaxMinutes
+	product.Vehicles = validateVehicles(in.Vehicles, validationErr)
+
+	if err := validationErr.orNil(); err != nil {
+		return Product{}, err
+	}
+	return product, nil
+}
+
+// validateProductWindow parse the entry or exit window of the product input, the window end can't be before its start.
+func validateProductWindow(field, times string, validationErr *ValidationError) (TimeWindow, bool) {
+	if times == "" {
+		validationErr.add(field, "is required")
+		return TimeWindow{}, false
+	}
+	window, err := ParseTimeWindow(times)
+	if err != nil {
+		validationErr.add(field, err.Error())
+		return TimeWindow{}, false
+	}
+	if window.End < window.Start {
+		validationErr.add(field, fmt.Sprintf("end '%s' is before start '%s'", window.End, window.Start))
+		return TimeWindow{}, false
+	}
+	return window, true
+}
+
+// Entry returns the entry window of the product.
+func (p Product) Entry() TimeWindow {
+	return TimeWindow{Start: p.EntryStart, End: p.EntryEnd}
+}
+
+// Exit returns the exit window of the product.
+func (p Product) Exit() TimeWindow {
+	return TimeWindow{Start: p.ExitStart, End: p.ExitEnd}
+}
+
+// Scale returns the product with the price multiplied, rounded to the nearest cent.
+func (p Product) Scale(multiplier float64) Product {
+	p.Price = int(math.Round(float64(p.Price) * multiplier))
+	return p
+}
+
+// Covers check if the stay is entered & left within the product windows of the same day in the location, the windows include their ends.
+func (p Product) Covers(start, end time.Time, loc *time.Location) bool {
+	if p.MaxMinutes > 0 && end.Sub(start) > time.Duration(p.MaxMinutes)*time.Minute {
+		return false
+	}
+
+	localStart, localEnd := start.In(loc), end.In(loc)
+	date := time.Date(localStart.Year(), localStart.Month(), localStart.Day(), 0, 0, 0, 0, loc)
+	if !p.Days.Has(date.Weekday()) {
+		return false
+	}
+	within := func(instant time.Time, window TimeWindow) bool {
+		return !instant.Before(window.Start.On(date)) && !instant.After(window.End.On(date))
+	}
+	return within(localStart, p.Entry()) && within(localEnd, p.Exit())
+}
+
+// Input returns the product in the wire format.
+func (p Product) Input() ProductInput {
+	price := p.Price
+	return ProductInput{
+		ID:         p.ID,
+		Name:       p.Name,
+		Days:       p.Days.String(),
+		Entry:      p.Entry().String(),
+		Exit:       p.Exit().String(),
+		Tz:         p.Tz,
+		Price:      &price,
+		MaxMinutes: p.MaxMinutes,
+		Vehicles:   p.Vehicles,
+	}
+}
+
+// MarshalJSON writes the product in the wire format: id, name, days, entry & exit windows, tz, price, max minutes & vehicle multipliers.
+func (p Product) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.Input())
+}
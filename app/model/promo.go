@@ -0,0 +1,170 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// PromoKind decides the discount of the promo code.
+type PromoKind string
+
+const (
+	// PromoPercentOff takes the percent off the price.
+	PromoPercentOff PromoKind = "percent_off"
+	// PromoFixedOff takes the amount in the currency off the price, down to free.
+	PromoFixedOff PromoKind = "fixed_off"
+	// PromoFreeFirstHour takes the price of the first hour of the stay off the price.
+	PromoFreeFirstHour PromoKind = "free_first_hour"
+)
+
+// FacilityIDs is the list of facility ids, stored as json in the DB.
+type FacilityIDs []uint
+
+// Value writes the facility ids as json into the DB.
+func (f FacilityIDs) Value() (driver.Value, error) {
+	if len(f) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(f)
+	return string(data), err
+}
+
+// Scan reads the facility ids from the json stored in the DB.
+func (f *FacilityIDs) Scan(value interface{}) error {
+	switch data := value.(type) {
+	case nil:
+		*f = nil
+		return nil
+	case string:
+		return json.Unmarshal([]byte(data), f)
+	case []byte:
+		return json.Unmarshal(data, f)
+	}
+	return fmt.Errorf("can't scan %T into facility ids", value)
+}
+
+// PromoCode struct for storing the promo code in DB, the code is unique & upper case.
+// The code is valid from valid from (always if nil) until valid to (always if nil), for max uses bookings (unlimited if 0)
+// at the facilities (every facility if empty). The amount of the fixed off code is in the currency, it only applies at the facilities of the currency.
+type PromoCode struct {
+	ID         uint        `gorm:"primaryKey" json:"id"`
+	Code       string      `gorm:"not null;uniqueIndex" json:"code"`
+	Kind       PromoKind   `gorm:"not null" json:"kind"`
+	Percent    int         `gorm:"not null;default:0" json:"percent,omitempty"`
+	Amount     int         `gorm:"not null;default:0" json:"amount,omitempty"`
+	Currency   Currency    `gorm:"not null;default:''" json:"currency,omitempty"`
+	ValidFrom  *time.Time  `json:"valid_from,omitempty"`
+	ValidTo    *time.Time  `json:"valid_to,omitempty"`
+	MaxUses    int         `gorm:"not null;default:0" json:"max_uses"`
+	Uses       int         `gorm:"not null;default:0" json:"uses"`
+	Facilities FacilityIDs `gorm:"type:text" json:"facilities,omitempty"`
+}
+
+// PromoCodeInput is the wire format of the promo code, the uses are counted by the bookings and ignored on input.
+type PromoCodeInput struct {
+	Code       string      `json:"code"`
+	Kind       string      `json:"kind"`
+	Percent    int         `json:"percent,omitempty"`
+	Amount     int         `json:"amount,omitempty"`
+	Currency   string      `json:"currency,omitempty"`
+	ValidFrom  *time.Time  `json:"valid_from,omitempty"`
+	ValidTo    *time.Time  `json:"valid_to,omitempty"`
+	MaxUses    int         `json:"max_uses,omitempty"`
+	Facilities FacilityIDs `json:"facilities,omitempty"`
+}
+
+// NormalizePromoCode returns the code as stored, the codes are matched regardless of case.
+func NormalizePromoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Validate check every field of the promo code input and returns the promo code, or the ValidationError with all the invalid fields.
+// The percent is only for the percent off code and the amount & its currency (USD by default) only for the fixed off code.
+func (in PromoCodeInput) Validate() (PromoCode, error) {
+	validationErr := &ValidationError{}
+	promo := PromoCode{Code: NormalizePromoCode(in.Code), Kind: PromoKind(in.Kind), ValidFrom: in.ValidFrom, ValidTo: in.ValidTo, Facilities: in.Facilities}
+
+	if promo.Code == "" {
+		validationErr.add("code", "is required")
+	} else if strings.ContainsAny(promo.Code, " \t") {
+		validationErr.add("code", fmt.Sprintf("'%s' can't have spaces", in.Code))
+	}
+
+	switch promo.Kind {
+	case PromoPercentOff:
+		if in.Percent <= 0 || in.Percent > 100 {
+			validationErr.add("percent", "must be 1 to 100")
+		}
+	case PromoFixedOff:
+		if in.Amount <= 0 {
+			validationErr.add("amount", "must be positive")
+		}
+	case PromoFreeFirstHour:
+	case "":
+		validationErr.add("kind", "is required")
+	default:
+		validationErr.add("kind", fmt.Sprintf("unknown kind '%s', isn't percent_off, fixed_off or free_first_hour", in.Kind))
+	}
+	if promo.Kind != PromoPercentOff && in.Percent != 0 {
+		validationErr.add("percent", "is only for the percent_off code")
+	}
+	if promo.Kind != PromoFixedOff && in.Amount != 0 {
+		validationErr.add("amount", "is only for the fixed_off code")
+	}
+	promo.Percent, promo.Amount = in.Percent, in.Amount
+	if promo.Kind == PromoFixedOff {
+		promo.Currency = validateAmountCurrency(in.Currency, validationErr)
+	} else if in.Currency != "" {
+		validationErr.add("currency", "is only for the fixed_off code")
+	}
+
+	if in.ValidFrom != nil && in.ValidTo != nil && !in.ValidTo.After(*in.ValidFrom) {
+		validationErr.add("valid_to", fmt.Sprintf("'%s' isn't after valid from '%s'", in.ValidTo.Format(time.RFC3339), in.ValidFrom.Format(time.RFC3339)))
+	}
+
+	if in.MaxUses < 0 {
+		validationErr.add("max_uses", "can't be negative")
+	}
+	promo.MaxUses = in.MaxUses
+
+	if err := validationErr.orNil(); err != nil {
+		return PromoCode{}, err
+	}
+	return promo, nil
+}
+
+// PromoError is the reason the promo code can't be applied.
+type PromoError struct {
+	Code   string
+	Reason string
+}
+
+// Error returns the promo code & the reason.
+func (e *PromoError) Error() string {
+	return fmt.Sprintf("promo code '%s' %s", e.Code, e.Reason)
+}
+
+// Check returns the PromoError if the promo code can't be applied at the facility at the instant, nil otherwise.
+func (p PromoCode) Check(facility uint, at time.Time) error {
+	switch {
+	case p.ValidFrom != nil && at.Before(*p.ValidFrom):
+		return &PromoError{Code: p.Code, Reason: fmt.Sprintf("isn't valid until %s", p.ValidFrom.UTC().Format(time.RFC3339))}
+	case p.ValidTo != nil && !at.Before(*p.ValidTo):
+		return &PromoError{Code: p.Code, Reason: fmt.Sprintf("expired at %s", p.ValidTo.UTC().Format(time.RFC3339))}
+	case p.MaxUses > 0 && p.Uses >= p.MaxUses:
+		return &PromoError{Code: p.Code, Reason: fmt.Sprintf("reached its limit of %d uses", p.MaxUses)}
+	}
+
+	if len(p.Facilities) == 0 {
+		return nil
+	}
+	for _, id := range p.Facilities {
+		if id == facility {
+			return nil
+		}
+	}
+	return &PromoError{Code: p.Code, Reason: fmt.Sprintf("isn't valid at facility '%d'", facility)}
+}
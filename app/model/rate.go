@@ -0,0 +1,197 @@
+package model
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+)
+
+// RateInput is the wire format of the rate, as in the rates.json file. The id is assigned by the DB and ignored on input.
+type RateInput struct {
+	ID        uint   `json:"id,omitempty"`
+	Days      string `json:"days"`
+	Times     string `json:"times"`
+	Tz        string `json:"tz"`
+	Price     *int   `json:"price"`
+	Priority  int    `json:"priority,omitempty"`
+	Kind      string `json:"kind,omitempty"`
+	Increment int    `json:"increment,omitempty"`
+	Rounding  string `json:"rounding,omitempty"`
+	Tiers     Tiers  `json:"tiers,omitempty"`
+
+	EffectiveFrom *time.Time `json:"effective_from,omitempty"`
+	EffectiveTo   *time.Time `json:"effective_to,omitempty"`
+
+	Vehicles VehicleMultipliers `json:"vehicles,omitempty"`
+}
+
+// RatesInput is the wire format of the rate list, as in the rates.json file.
+type RatesInput struct {
+	Rates []RateInput `json:"rates"`
+}
+
+// NewRate returns the validated rate from the days & times strings of the wire format.
+func NewRate(days, times, tz string, price int) (Rate, error) {
+	return RateInput{Days: days, Times: times, Tz: tz, Price: &price}.Validate()
+}
+
+// CoversWeekday check if the rate days contains the given weekday.
+func (r Rate) CoversWeekday(weekday time.Weekday) bool {
+	return r.Days.Has(weekday)
+}
+
+// InForce check if the rate is effective at the instant, regardless of the later versions of the rate window.
+func (r Rate) InForce(at time.Time) bool {
+	return (r.EffectiveFrom == nil || !at.Before(*r.EffectiveFrom)) && (r.EffectiveTo == nil || at.Before(*r.EffectiveTo))
+}
+
+// Scale returns the rate with the price & tier prices multiplied, rounded to the nearest cent.
+func (r Rate) Scale(multiplier float64) Rate {
+	r.Price = int(math.Round(float64(r.Price) * multiplier))
+	if len(r.Tiers) > 0 {
+		tiers := make(Tiers, len(r.Tiers))
+		for i, tier := range r.Tiers {
+			tiers[i] = Tier{Minutes: tier.Minutes, Price: int(math.Round(float64(tier.Price) * multiplier))}
+		}
+		r.Tiers = tiers
+	}
+	return r
+}
+
+// Window returns the time window of the rate.
+func (r Rate) Window() TimeWindow {
+	return TimeWindow{Start: r.StartTime, End: r.EndTime}
+}
+
+// Input returns the rate in the wire format, the kind, increment & rounding are left out for the flat rate.
+func (r Rate) Input() RateInput {
+	price := r.Price
+	input := RateInput{
+		ID:       r.ID,
+		Days:     r.Days.String(),
+		Times:    r.Window().String(),
+		Tz:       r.Tz,
+		Price:    &price,
+		Priority: r.Priority,
+	}
+	input.EffectiveFrom, input.EffectiveTo = r.EffectiveFrom, r.EffectiveTo
+	input.Vehicles = r.Vehicles
+	if r.Kind != KindFlat && r.Kind != "" {
+		input.Kind, input.Increment, input.Rounding, input.Tiers = string(r.Kind), r.Increment, string(r.Rounding), r.Tiers
+	}
+	return input
+}
+
+// MarshalJSON writes the rate in the wire format: id, days, times, tz, price, priority, the kind fields, the effective dates & the vehicle multipliers.
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.Input())
+}
+
+// UnmarshalJSON reads the rate from the wire format: days, times, tz, price, priority, the kind fields, the effective dates & the vehicle multipliers, the rate is validated.
+// The id is kept as it's when reading back a stored rate.
+func (r *Rate) UnmarshalJSON(data []byte) error {
+	var input RateInput
+	if err := json.Unmarshal(data, &input); err != nil {
+		return err
+	}
+
+	rate, err := input.Validate()
+	if err != nil {
+		return err
+	}
+	rate.ID = input.ID
+	*r = rate
+	return nil
+}
+
+// RatePatch is the wire format of the rate fields to change, fields which aren't supplied are kept as they are.
+type RatePatch struct {
+	Days      *string `json:"days"`
+	Times     *string `json:"times"`
+	Tz        *string `json:"tz"`
+	Price     *int    `json:"price"`
+	Priority  *int    `json:"priority"`
+	Kind      *string `json:"kind"`
+	Increment *int    `json:"increment"`
+	Rounding  *string `json:"rounding"`
+	Tiers     *Tiers  `json:"tiers"`
+
+	EffectiveFrom *time.Time `json:"effective_from"`
+	EffectiveTo   *time.Time `json:"effective_to"`
+
+	Vehicles *VehicleMultipliers `json:"vehicles"`
+}
+
+// Apply returns the validated rate with the supplied fields of the patch changed, the id & facility are kept.
+func (p RatePatch) Apply(rate Rate) (Rate, error) {
+	input := rate.Input()
+	if p.Days != nil {
+		input.Days = *p.Days
+	}
+	if p.Times != nil {
+		input.Times = *p.Times
+	}
+	if p.Tz != nil {
+		input.Tz = *p.Tz
+	}
+	if p.Price != nil {
+		input.Price = p.Price
+	}
+	if p.Priority != nil {
+		input.Priority = *p.Priority
+	}
+	if p.Kind != nil {
+		input.Kind = *p.Kind
+	}
+	if p.Increment != nil {
+		input.Increment = *p.Increment
+	}
+	if p.Rounding != nil {
+		input.Rounding = *p.Rounding
+	}
+	if p.Tiers != nil {
+		input.Tiers = *p.Tiers
+	}
+	if p.EffectiveFrom != nil {
+		input.EffectiveFrom = p.EffectiveFrom
+	}
+	if p.EffectiveTo != nil {
+		input.EffectiveTo = p.EffectiveTo
+	}
+	if p.Vehicles != nil {
+		input.Vehicles = *p.Vehicles
+	}
+
+	patched, err := input.Validate()
+	if err != nil {
+		return Rate{}, err
+	}
+	patched.ID, patched.FacilityID = rate.ID, rate.FacilityID
+	return patched, nil
+}
+
+// RateOutput is the wire format of the rate in the responses, the price is the money in the facility currency.
+// The tier prices stay in the minor unit of the same currency.
+type RateOutput struct {
+	RateInput
+	Price Money `json:"price"`
+}
+
+// RatesOutput is the wire format of the rate list in the responses.
+type RatesOutput struct {
+	Rates []RateOutput `json:"rates"`
+}
+
+// Output returns the rate in the response wire format, with the price in the currency.
+func (r Rate) Output(currency Currency) RateOutput {
+	return RateOutput{RateInput: r.Input(), Price: Money{Amount: r.Price, Currency: currency}}
+}
+
+// NewRatesOutput returns the rates in the response wire format, with the prices in the currency.
+func NewRatesOutput(rates []Rate, currency Currency) RatesOutput {
+	output := RatesOutput{Rates: make([]RateOutput, len(rates))}
+	for i, rate := range rates {
+		output.Rates[i] = rate.Output(currency)
+	}
+	return output
+}
@@ -0,0 +1,95 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Reservation struct for storing the booked stay of a vehicle in DB, the price is locked in at the booking with the promo code discount if any,
+// and the total with the taxes & fees, both in the currency of the facility.
+type Reservation struct {
+	ID         uint         `gorm:"primaryKey" json:"id"`
+	FacilityID uint         `gorm:"not null;index:idx_reservation_interval" json:"facility_id"`
+	Start      time.Time    `gorm:"column:start_at;not null;index:idx_reservation_interval" json:"start"`
+	End        time.Time    `gorm:"column:end_at;not null;index:idx_reservation_interval" json:"end"`
+	Vehicle    VehicleClass `gorm:"not null;default:standard" json:"vehicle"`
+	Price      int          `gorm:"not null" json:"price"`
+	Promo      string       `gorm:"not null;default:''" json:"promo,omitempty"`
+	Total      int          `gorm:"not null;default:0" json:"total"`
+	Currency   Currency     `gorm:"not null;default:'USD'" json:"currency"`
+	CreatedAt  time.Time    `gorm:"not null" json:"created_at"`
+}
+
+// ReservationInput is the wire format of the reservation to book, the facility is of the route.
+type ReservationInput struct {
+	Start   *time.Time `json:"start"`
+	End     *time.Time `json:"end"`
+	Vehicle string     `json:"vehicle,omitempty"`
+	Promo   string     `json:"promo,omitempty"`
+}
+
+// Validate check every field of the reservation input and returns the reservation, or the ValidationError with all the invalid fields.
+// The start & end are stored in UTC, the vehicle is standard by default and the promo code is optional.
+func (in ReservationInput) Validate() (Reservation, error) {
+	validationErr := &ValidationError{}
+	reservation := Reservation{Promo: NormalizePromoCode(in.Promo)}
+
+	if in.Start == nil {
+		validationErr.add("start", "is required")
+	} else {
+		reservation.Start = in.Start.UTC()
+	}
+
+	if in.End == nil {
+		validationErr.add("end", "is required")
+	} else if in.Start != nil && !in.End.After(*in.Start) {
+		validationErr.add("end", fmt.Sprintf("'%s' isn't after start '%s'", in.End.Format(time.RFC3339), in.Start.Format(time.RFC3339)))
+	} else {
+		reservation.End = in.End.UTC()
+	}
+
+	if vehicle, err := ParseVehicleClass(in.Vehicle); err != nil {
+		validationErr.add("vehicle", err.Error())
+	} else {
+		reservation.Vehicle = vehicle
+	}
+
+	if err := validationErr.orNil(); err != nil {
+		return Reservation{}, err
+	}
+	return reservation, nil
+}
+
+// PeakOccupancy returns the most reservations holding a space at the same instant between start & end.
+// A reservation holds its space from its start until its end, the space is free again at the end.
+func PeakOccupancy(reservations []Reservation, start, end time.Time) int {
+	type change struct {
+		at    time.Time
+		delta int
+	}
+
+	var changes []change
+	for _, reservation := range reservations {
+		if !reservation.Start.Before(end) || !reservation.End.After(start) {
+			continue
+		}
+		changes = append(changes, change{at: reservation.Start, delta: 1}, change{at: reservation.End, delta: -1})
+	}
+	// the space freed at an instant is counted before the space taken at the same instant
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].at.Equal(changes[j].at) {
+			return changes[i].delta < changes[j].delta
+		}
+		return changes[i].at.Before(changes[j].at)
+	})
+
+	occupancy, peak := 0, 0
+	for _, c := range changes {
+		occupancy += c.delta
+		if occupancy > peak {
+			peak = occupancy
+		}
+	}
+	return peak
+}
@@ -0,0 +1,100 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// SurgeBand multiplies the price of the stay when the facility occupancy reaches the band occupancy percent.
+type SurgeBand struct {
+	Occupancy  int     `json:"occupancy"`
+	Multiplier float64 `json:"multiplier"`
+}
+
+// SurgeBands is the list of surge bands by ascending occupancy, stored as json in the DB.
+type SurgeBands []SurgeBand
+
+// Value writes the bands as json into the DB.
+func (b SurgeBands) Value() (driver.Value, error) {
+	if len(b) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(b)
+	return string(data), err
+}
+
+// Scan reads the bands from the json stored in the DB.
+func (b *SurgeBands) Scan(value interface{}) error {
+	switch data := value.(type) {
+	case nil:
+		*b = nil
+		return nil
+	case string:
+		return json.Unmarshal([]byte(data), b)
+	case []byte:
+		return json.Unmarshal(data, b)
+	}
+	return fmt.Errorf("can't scan %T into surge bands", value)
+}
+
+// Surge contains the occupancy surge settings of the facility, stored with the facility id.
+// The multiplier of the highest band reached by the occupancy is clamped to the min & max multiplier (zero disables a clamp),
+// the surge is off unless enabled.
+type Surge struct {
+	ID            uint       `gorm:"primaryKey" json:"-"`
+	Enabled       bool       `gorm:"not null;default:false" json:"enabled"`
+	Bands         SurgeBands `gorm:"type:text" json:"bands"`
+	MinMultiplier float64    `gorm:"not null;default:0" json:"min_multiplier"`
+	MaxMultiplier float64    `gorm:"not null;default:0" json:"max_multiplier"`
+}
+
+// Validate check the bands are of ascending occupancy percents with positive multipliers and the clamps are in order,
+// returns the ValidationError with all the invalid fields.
+func (s Surge) Validate() error {
+	validationErr := &ValidationError{}
+	for i, band := range s.Bands {
+		field := fmt.Sprintf("bands[%d]", i)
+		if band.Occupancy < 0 || band.Occupancy > 100 {
+			validationErr.add(field+".occupancy", "must be 0 to 100")
+		} else if i > 0 && band.Occupancy <= s.Bands[i-1].Occupancy {
+			validationErr.add(field+".occupancy", fmt.Sprintf("'%d' isn't above the previous band occupancy '%d'", band.Occupancy, s.Bands[i-1].Occupancy))
+		}
+		if band.Multiplier <= 0 {
+			validationErr.add(field+".multiplier", "must be positive")
+		}
+	}
+
+	if s.MinMultiplier < 0 {
+		validationErr.add("min_multiplier", "can't be negative")
+	}
+	if s.MaxMultiplier < 0 {
+		validationErr.add("max_multiplier", "can't be negative")
+	} else if s.MaxMultiplier > 0 && s.MaxMultiplier < s.MinMultiplier {
+		validationErr.add("max_multiplier", fmt.Sprintf("'%g' is below min multiplier '%g'", s.MaxMultiplier, s.MinMultiplier))
+	}
+	return validationErr.orNil()
+}
+
+// Multiplier returns the clamped multiplier of the highest band reached by the occupancy of the capacity, 1 if none is reached.
+// The facility without capacity has no occupancy.
+func (s Surge) Multiplier(occupancy, capacity int) float64 {
+	multiplier := 1.0
+	if capacity <= 0 {
+		return multiplier
+	}
+	for _, band := range s.Bands {
+		if occupancy*100 >= band.Occupancy*capacity {
+			multiplier = band.Multiplier
+		}
+	}
+
+	if s.MinMultiplier > 0 {
+		multiplier = math.Max(multiplier, s.MinMultiplier)
+	}
+	if s.MaxMultiplier > 0 {
+		multiplier = math.Min(multiplier, s.MaxMultiplier)
+	}
+	return multiplier
+}
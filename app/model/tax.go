@@ -0,0 +1,102 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TaxKind decides how the tax or fee of the rule is charged on the price.
+type TaxKind string
+
+const (
+	// TaxPercent charges the basis points of the price, 1025 basis points is 10.25%.
+	TaxPercent TaxKind = "percent"
+	// TaxFlat charges the amount in the currency once per entry.
+	TaxFlat TaxKind = "flat"
+)
+
+// basisPointsPerUnit is the basis points of the whole price.
+const basisPointsPerUnit = 10000
+
+// TaxRule struct for storing the tax or fee charged on top of the price in DB, e.g. the city parking tax or the service fee.
+// The rule is either of the facility or of every facility in the jurisdiction, the amount of the flat rule is in its currency
+// and isn't charged at the facilities of another currency.
+type TaxRule struct {
+	ID           uint     `gorm:"primaryKey" json:"id"`
+	Name         string   `gorm:"not null" json:"name"`
+	Kind         TaxKind  `gorm:"not null" json:"kind"`
+	BasisPoints  int      `gorm:"not null;default:0" json:"basis_points,omitempty"`
+	Amount       int      `gorm:"not null;default:0" json:"amount,omitempty"`
+	Currency     Currency `gorm:"not null;default:''" json:"currency,omitempty"`
+	FacilityID   uint     `gorm:"not null;default:0;index" json:"facility_id,omitempty"`
+	Jurisdiction string   `gorm:"not null;default:'';index" json:"jurisdiction,omitempty"`
+}
+
+// TaxRuleInput is the wire format of the tax rule.
+type TaxRuleInput struct {
+	Name         string `json:"name"`
+	Kind         string `json:"kind"`
+	BasisPoints  int    `json:"basis_points,omitempty"`
+	Amount       int    `json:"amount,omitempty"`
+	Currency     string `json:"currency,omitempty"`
+	FacilityID   uint   `json:"facility_id,omitempty"`
+	Jurisdiction string `json:"jurisdiction,omitempty"`
+}
+
+// Validate check every field of the tax rule input and returns the tax rule, or the ValidationError with all the invalid fields.
+// The basis points are only for the percent rule and the amount & its currency (USD by default) only for the flat rule, the rule has either the facility or the jurisdiction.
+func (in TaxRuleInput) Validate() (TaxRule, error) {
+	validationErr := &ValidationError{}
+	rule := TaxRule{Name: strings.TrimSpace(in.Name), Kind: TaxKind(in.Kind), FacilityID: in.FacilityID, Jurisdiction: strings.TrimSpace(in.Jurisdiction)}
+
+	if rule.Name == "" {
+		validationErr.add("name", "is required")
+	}
+
+	switch rule.Kind {
+	case TaxPercent:
+		if in.BasisPoints <= 0 || in.BasisPoints > basisPointsPerUnit {
+			validationErr.add("basis_points", fmt.Sprintf("must be 1 to %d", basisPointsPerUnit))
+		}
+	case TaxFlat:
+		if in.Amount <= 0 {
+			validationErr.add("amount", "must be positive")
+		}
+	case "":
+		validationErr.add("kind", "is required")
+	default:
+		validationErr.add("kind", fmt.Sprintf("unknown kind '%s', isn't percent or flat", in.Kind))
+	}
+	if rule.Kind != TaxPercent && in.BasisPoints != 0 {
+		validationErr.add("basis_points", "is only for the percent rule")
+	}
+	if rule.Kind != TaxFlat && in.Amount != 0 {
+		validationErr.add("amount", "is only for the flat rule")
+	}
+	rule.BasisPoints, rule.Amount = in.BasisPoints, in.Amount
+	if rule.Kind == TaxFlat {
+		rule.Currency = validateAmountCurrency(in.Currency, validationErr)
+	} else if in.Currency != "" {
+		validationErr.add("currency", "is only for the flat rule")
+	}
+
+	if (rule.FacilityID == 0) == (rule.Jurisdiction == "") {
+		validationErr.add("facility_id", "either the facility or the jurisdiction is required")
+	}
+
+	if err := validationErr.orNil(); err != nil {
+		return TaxRule{}, err
+	}
+	return rule, nil
+}
+
+// Charge returns the tax or fee of the rule on the price in the minor unit of its currency, the percent is rounded half up with integer math.
+func (r TaxRule) Charge(price int) int {
+	switch r.Kind {
+	case TaxPercent:
+		return (price*r.BasisPoints + basisPointsPerUnit/2) / basisPointsPerUnit
+	case TaxFlat:
+		return r.Amount
+	}
+	return 0
+}
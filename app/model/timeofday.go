@@ -0,0 +1,88 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MinutesPerDay is the number of minutes in a day, "2400" is the end of the day.
+const MinutesPerDay = 24 * 60
+
+// TimeOfDay is the minutes passed since midnight, it's written as "HHMM" in the rates.
+type TimeOfDay int
+
+// ParseTimeOfDay parse the "HHMM" string into TimeOfDay, valid values are from "0000" to "2400".
+func ParseTimeOfDay(hhmm string) (TimeOfDay, error) {
+	formatErr := fmt.Errorf("time of day '%s' isn't as per HHMM format", hhmm)
+	if len(hhmm) != 4 {
+		return 0, formatErr
+	}
+
+	hour, hourErr := strconv.ParseUint(hhmm[0:2], 10, 8)
+	minute, minuteErr := strconv.ParseUint(hhmm[2:4], 10, 8)
+	if hourErr != nil || minuteErr != nil || minute > 59 {
+		return 0, formatErr
+	}
+
+	timeOfDay := TimeOfDay(hour*60 + minute)
+	if timeOfDay > MinutesPerDay {
+		return 0, formatErr
+	}
+	return timeOfDay, nil
+}
+
+// Hour returns the hour of the time of day.
+func (t TimeOfDay) Hour() int {
+	return int(t) / 60
+}
+
+// Minute returns the minute within the hour of the time of day.
+func (t TimeOfDay) Minute() int {
+	return int(t) % 60
+}
+
+// String returns the time of day in "HHMM" format.
+func (t TimeOfDay) String() string {
+	return fmt.Sprintf("%02d%02d", t.Hour(), t.Minute())
+}
+
+// On returns the time of day on the date of the given time, in the location of the given time.
+func (t TimeOfDay) On(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), t.Hour(), t.Minute(), 0, 0, date.Location())
+}
+
+// TimeWindow is the start & end time of day of a rate.
+type TimeWindow struct {
+	Start TimeOfDay
+	End   TimeOfDay
+}
+
+// ParseTimeWindow parse the "HHMM-HHMM" string into TimeWindow.
+func ParseTimeWindow(times string) (TimeWindow, error) {
+	splitTime := strings.Split(times, "-") // format: "start-end", split time strings into two parts
+
+	// after split, it should be into two parts.
+	if len(splitTime) != 2 {
+		return TimeWindow{}, errors.New("time value is not as per the standard")
+	}
+
+	start, err := ParseTimeOfDay(splitTime[0])
+	if err != nil {
+		return TimeWindow{}, err
+	}
+
+	end, err := ParseTimeOfDay(splitTime[1])
+	if err != nil {
+		return TimeWindow{}, err
+	}
+
+	return TimeWindow{Start: start, End: end}, nil
+}
+
+// String returns the time window in "HHMM-HHMM" format.
+func (w TimeWindow) String() string {
+	return w.Start.String() + "-" + w.End.String()
+}
@@ -0,0 +1,190 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// FieldError is the validation error of a single field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError contains the errors of every invalid field.
+type ValidationError struct {
+	Fields []FieldError `json:"fields"`
+}
+
+// Error returns the field errors joined into one message.
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		messages[i] = field.Field + ": " + field.Message
+	}
+	return "validation failed, " + strings.Join(messages, "; ")
+}
+
+// add appends the field error.
+func (e *ValidationError) add(field, message string) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: message})
+}
+
+// addAll appends the field errors of the nested validation error, prefixing the field names.
+func (e *ValidationError) addAll(prefix string, nested *ValidationError) {
+	for _, field := range nested.Fields {
+		e.add(prefix+"."+field.Field, field.Message)
+	}
+}
+
+// orNil returns nil if there is no field error.
+func (e *ValidationError) orNil() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Validate check every field of the rate input and returns the rate, or the ValidationError with all the invalid fields.
+func (in RateInput) Validate() (Rate, error) {
+	validationErr := &ValidationError{}
+	rate := Rate{Tz: in.Tz, Priority: in.Priority}
+
+	if strings.TrimSpace(in.Days) == "" {
+		validationErr.add("days", "is required")
+	} else if weekdays, err := ParseWeekdays(in.Days); err != nil {
+		validationErr.add("days", err.Error())
+	} else {
+		rate.Days = weekdays
+	}
+
+	if in.Times == "" {
+		validationErr.add("times", "is required")
+	} else if window, err := ParseTimeWindow(in.Times); err != nil {
+		validationErr.add("times", err.Error())
+	} else if window.End <= window.Start {
+		validationErr.add("times", fmt.Sprintf("end '%s' isn't after start '%s'", window.End, window.Start))
+	} else {
+		rate.StartTime, rate.EndTime = window.Start, window.End
+	}
+
+	if in.Tz == "" {
+		validationErr.add("tz", "is required")
+	} else if _, err := time.LoadLocation(in.Tz); err != nil || in.Tz == "Local" {
+		validationErr.add("tz", fmt.Sprintf("'%s' isn't an IANA time zone", in.Tz))
+	}
+
+	if in.Price == nil {
+		validationErr.add("price", "is required")
+	} else if *in.Price < 0 {
+		validationErr.add("price", "can't be negative")
+	} else {
+		rate.Price = *in.Price
+	}
+
+	in.validateKind(&rate, validationErr)
+	rate.Vehicles = validateVehicles(in.Vehicles, validationErr)
+
+	if in.EffectiveFrom != nil && in.EffectiveTo != nil && !in.EffectiveTo.After(*in.EffectiveFrom) {
+		validationErr.add("effective_to", fmt.Sprintf("'%s' isn't after effective from '%s'", in.EffectiveTo.Format(time.RFC3339), in.EffectiveFrom.Format(time.RFC3339)))
+	} else {
+		rate.EffectiveFrom, rate.EffectiveTo = in.EffectiveFrom, in.EffectiveTo
+	}
+
+	if err := validationErr.orNil(); err != nil {
+		return Rate{}, err
+	}
+	return rate, nil
+}
+
+// validateKind check the kind, increment, rounding & tiers of the rate input and sets them on the rate.
+// The flat rate has none of them, the increment & tiered rates need the increment minutes, the rounding is up by default.
+func (in RateInput) validateKind(rate *Rate, validationErr *ValidationError) {
+	rate.Kind, rate.Rounding = KindFlat, RoundUp
+	switch kind := RateKind(in.Kind); kind {
+	case "", KindFlat:
+		if in.Increment != 0 {
+			validationErr.add("increment", "isn't allowed for the flat rate")
+		}
+		if in.Rounding != "" {
+			validationErr.add("rounding", "isn't allowed for the flat rate")
+		}
+		if in.Tiers != nil {
+			validationErr.add("tiers", "isn't allowed for the flat rate")
+		}
+		return
+	case KindIncrement, KindTiered:
+		rate.Kind = kind
+	default:
+		validationErr.add("kind", fmt.Sprintf("unknown kind '%s', isn't flat, increment or tiered", in.Kind))
+		return
+	}
+
+	if in.Increment <= 0 || in.Increment > MinutesPerDay {
+		validationErr.add("increment", fmt.Sprintf("must be 1 to %d minutes", MinutesPerDay))
+	} else {
+		rate.Increment = in.Increment
+	}
+
+	switch rounding := Rounding(in.Rounding); rounding {
+	case "":
+	case RoundUp, RoundDown, RoundNearest:
+		rate.Rounding = rounding
+	default:
+		validationErr.add("rounding", fmt.Sprintf("unknown rounding '%s', isn't up, down or nearest", in.Rounding))
+	}
+
+	if rate.Kind == KindIncrement {
+		if in.Tiers != nil {
+			validationErr.add("tiers", "isn't allowed for the increment rate")
+		}
+		return
+	}
+
+	if len(in.Tiers) == 0 {
+		validationErr.add("tiers", "is required")
+	}
+	for i, tier := range in.Tiers {
+		field := fmt.Sprintf("tiers[%d]", i)
+		if tier.Minutes <= 0 || (rate.Increment > 0 && tier.Minutes%rate.Increment != 0) {
+			validationErr.add(field+".minutes", "must be a positive multiple of the increment")
+		}
+		if tier.Price < 0 {
+			validationErr.add(field+".price", "can't be negative")
+		}
+	}
+	rate.Tiers = in.Tiers
+}
+
+// Validate check every rate of the input list, field names of the errors are prefixed with the rate index e.g. "rates[1].tz".
+// The list is required and can't have two rates with the same days, times & tz.
+func (in RatesInput) Validate() ([]Rate, error) {
+	validationErr := &ValidationError{}
+	if in.Rates == nil {
+		validationErr.add("rates", "is required")
+	}
+
+	rates := make([]Rate, 0, len(in.Rates))
+	indexes := make([]int, 0, len(in.Rates))
+	for i, rateInput := range in.Rates {
+		rate, err := rateInput.Validate()
+		if err != nil {
+			validationErr.addAll(fmt.Sprintf("rates[%d]", i), err.(*ValidationError))
+			continue
+		}
+
+		for j, other := range rates {
+			if rate.SameKey(other) {
+				validationErr.add(fmt.Sprintf("rates[%d]", i), fmt.Sprintf("duplicates rates[%d]", indexes[j]))
+			}
+		}
+		rates = append(rates, rate)
+		indexes = append(indexes, i)
+	}
+
+	if err := validationErr.orNil(); err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
@@ -0,0 +1,101 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// VehicleClass is the class of the parked vehicle, the rate price is of the standard vehicle.
+type VehicleClass string
+
+const (
+	// VehicleCompact is the compact car.
+	VehicleCompact VehicleClass = "compact"
+	// VehicleStandard is the standard car, charged the rate price (default).
+	VehicleStandard VehicleClass = "standard"
+	// VehicleOversize is the oversize vehicle e.g. SUV, van or truck.
+	VehicleOversize VehicleClass = "oversize"
+	// VehicleMotorcycle is the motorcycle.
+	VehicleMotorcycle VehicleClass = "motorcycle"
+)
+
+// ParseVehicleClass returns the vehicle class of the name, the empty name is the standard vehicle.
+func ParseVehicleClass(name string) (VehicleClass, error) {
+	switch class := VehicleClass(name); class {
+	case "":
+		return VehicleStandard, nil
+	case VehicleCompact, VehicleStandard, VehicleOversize, VehicleMotorcycle:
+		return class, nil
+	}
+	return "", fmt.Errorf("unknown vehicle '%s', isn't compact, standard, oversize or motorcycle", name)
+}
+
+// VehicleMultipliers are the multipliers of the rate or product prices per vehicle class, stored as json in the DB.
+// The vehicle class without a multiplier is charged the rate or product price.
+type VehicleMultipliers map[VehicleClass]float64
+
+// Value writes the multipliers as json into the DB.
+func (v VehicleMultipliers) Value() (driver.Value, error) {
+	if len(v) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(v)
+	return string(data), err
+}
+
+// Scan reads the multipliers from the json stored in the DB.
+func (v *VehicleMultipliers) Scan(value interface{}) error {
+	switch data := value.(type) {
+	case nil:
+		*v = nil
+		return nil
+	case string:
+		return json.Unmarshal([]byte(data), v)
+	case []byte:
+		return json.Unmarshal(data, v)
+	}
+	return fmt.Errorf("can't scan %T into vehicle multipliers", value)
+}
+
+// ForVehicle returns the rate with the price & tier prices multiplied for the vehicle class.
+func (r Rate) ForVehicle(class VehicleClass) Rate {
+	multiplier, isSet := r.Vehicles[class]
+	if !isSet {
+		return r
+	}
+	return r.Scale(multiplier)
+}
+
+// ForVehicle returns the product with the price multiplied for the vehicle class.
+func (p Product) ForVehicle(class VehicleClass) Product {
+	multiplier, isSet := p.Vehicles[class]
+	if !isSet {
+		return p
+	}
+	return p.Scale(multiplier)
+}
+
+// validateVehicles check the vehicle classes & multipliers of the rate or product input, and returns them if any.
+func validateVehicles(vehicles VehicleMultipliers, validationErr *ValidationError) VehicleMultipliers {
+	classes := make([]string, 0, len(vehicles))
+	for class := range vehicles {
+		classes = append(classes, string(class))
+	}
+	sort.Strings(classes)
+
+	for _, name := range classes {
+		class, multiplier := VehicleClass(name), vehicles[VehicleClass(name)]
+		field := fmt.Sprintf("vehicles.%s", class)
+		if _, err := ParseVehicleClass(string(class)); err != nil || class == "" {
+			validationErr.add(field, fmt.Sprintf("unknown vehicle '%s', isn't compact, standard, oversize or motorcycle", class))
+		} else if multiplier <= 0 {
+			validationErr.add(field, "multiplier must be positive")
+		}
+	}
+	if len(vehicles) == 0 {
+		return nil
+	}
+	return vehicles
+}
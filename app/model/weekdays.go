@@ -0,0 +1,57 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Weekdays is the set of weekdays stored as a bitmask, bit n is set for time.Weekday(n).
+type Weekdays uint8
+
+// AllWeekdays is the set of every weekday.
+const AllWeekdays Weekdays = 1<<7 - 1
+
+// weekdayNames are the names of the weekdays as written in the rates, indexed by time.Weekday.
+var weekdayNames = [7]string{"sun", "mon", "tues", "wed", "thurs", "fri", "sat"}
+
+// weekdayAliases maps the accepted spellings of a weekday name.
+var weekdayAliases = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday,
+	"tue": time.Tuesday, "tues": time.Tuesday, "tuesday": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday,
+	"thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday, "thursday": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday,
+}
+
+// ParseWeekdays parse the comma separated weekday names (e.g. "mon,tues,thurs") into Weekdays.
+func ParseWeekdays(days string) (Weekdays, error) {
+	var weekdays Weekdays
+	for _, day := range strings.Split(days, ",") {
+		weekday, isKnown := weekdayAliases[strings.ToLower(strings.TrimSpace(day))]
+		if !isKnown {
+			return 0, fmt.Errorf("unknown day '%s'", day)
+		}
+		weekdays |= 1 << weekday
+	}
+	return weekdays, nil
+}
+
+// Has check if the weekday is in the set.
+func (d Weekdays) Has(weekday time.Weekday) bool {
+	return d&(1<<weekday) != 0
+}
+
+// String returns the comma separated weekday names, starting from monday.
+func (d Weekdays) String() string {
+	var names []string
+	for i := 1; i <= 7; i++ {
+		weekday := time.Weekday(i % 7)
+		if d.Has(weekday) {
+			names = append(names, weekdayNames[weekday])
+		}
+	}
+	return strings.Join(names, ",")
+}
@@ -0,0 +1,41 @@
+package pricing
+
+import (
+	"fmt"
+	"math"
+	"spotHero/app/model"
+	"time"
+)
+
+// PricingAdjuster adjusts the quoted price of the stay, after the rates, products & caps and before the promo code discount.
+type PricingAdjuster interface {
+	// Adjust returns the adjustment of the quoted price of the stay, the zero amount leaves the price as it's.
+	Adjust(quote *Quote, start, end time.Time) (Adjustment, error)
+}
+
+// OccupancyCounter returns the most reservations holding a space of the facility at the same instant between start & end.
+type OccupancyCounter func(start, end time.Time) (int, error)
+
+// OccupancySurge is the PricingAdjuster multiplying the price by the surge band reached by the facility occupancy over the stay.
+type OccupancySurge struct {
+	Surge     model.Surge
+	Capacity  int
+	Occupancy OccupancyCounter
+}
+
+// Adjust returns the surge adjustment of the quoted price, no adjustment when the surge is off.
+func (o OccupancySurge) Adjust(quote *Quote, start, end time.Time) (Adjustment, error) {
+	if !o.Surge.Enabled || o.Capacity <= 0 {
+		return Adjustment{}, nil
+	}
+
+	occupancy, err := o.Occupancy(start, end)
+	if err != nil {
+		return Adjustment{}, err
+	}
+	multiplier := o.Surge.Multiplier(occupancy, o.Capacity)
+	return Adjustment{
+		Name:   fmt.Sprintf("occupancy surge x%g", multiplier),
+		Amount: int(math.Round(float64(quote.Price)*multiplier)) - quote.Price,
+	}, nil
+}
@@ -0,0 +1,50 @@
+package pricing
+
+import (
+	"spotHero/app/model"
+	"time"
+)
+
+// MaxStay is the longest stay the engine quotes.
+const MaxStay = 31 * 24 * time.Hour
+
+// applyCaps adjust the quote price with the caps: the daily max caps the charge of every 24 hours from the start,
+// the weekly max caps the charge of every 7 days from the start and the minimum charge is the least price.
+// A segment is counted in the 24 hours it starts in.
+func applyCaps(quote *Quote, caps model.PriceCaps, start time.Time) {
+	if caps.DailyMax > 0 || caps.WeeklyMax > 0 {
+		dayTotals := map[int]int{}
+		for _, segment := range quote.Segments {
+			dayTotals[int(segment.Start.Sub(start)/(24*time.Hour))] += segment.Price
+		}
+
+		dailyCapped, weekTotals := 0, map[int]int{}
+		for day, total := range dayTotals {
+			if caps.DailyMax > 0 && total > caps.DailyMax {
+				total = caps.DailyMax
+			}
+			dailyCapped += total
+			weekTotals[day/7] += total
+		}
+
+		weeklyCapped := 0
+		for _, total := range weekTotals {
+			if caps.WeeklyMax > 0 && total > caps.WeeklyMax {
+				total = caps.WeeklyMax
+			}
+			weeklyCapped += total
+		}
+
+		// caps only lower the price, e.g. the max policy price may already be under them
+		if dailyCapped < quote.Price {
+			quote.adjust("daily max", dailyCapped-quote.Price)
+		}
+		if weeklyCapped < quote.Price {
+			quote.adjust("weekly max", weeklyCapped-quote.Price)
+		}
+	}
+
+	if caps.MinCharge > quote.Price {
+		quote.adjust("minimum charge", caps.MinCharge-quote.Price)
+	}
+}
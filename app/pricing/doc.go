@@ -0,0 +1,2 @@
+// Package pricing contains the pricing engine which quotes a parking stay against the stored rates.
+package pricing
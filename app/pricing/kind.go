@@ -0,0 +1,39 @@
+package pricing
+
+import (
+	"spotHero/app/model"
+	"time"
+)
+
+// amount returns the price of the rate for the billed time, as per the rate kind:
+// the flat price, the price for every increment or the tier prices followed by the price for every increment.
+func amount(rate model.Rate, billed time.Duration) int {
+	switch rate.Kind {
+	case model.KindIncrement, model.KindTiered:
+	default:
+		return rate.Price
+	}
+
+	increments := rate.Rounding.Increments(billed, time.Duration(rate.Increment)*time.Minute)
+	total := 0
+	for _, tier := range rate.Tiers {
+		tierIncrements := tier.Minutes / rate.Increment
+		if increments < tierIncrements {
+			tierIncrements = increments
+		}
+		total += tierIncrements * tier.Price
+		increments -= tierIncrements
+	}
+	return total + increments*rate.Price
+}
+
+// billedTime returns the time billed by the rate for the stay time, the increment & tiered rates bill whole increments
+// as per the rate rounding while the flat rate bills the stay time as it's.
+func billedTime(rate model.Rate, stay time.Duration) time.Duration {
+	switch rate.Kind {
+	case model.KindIncrement, model.KindTiered:
+		increment := time.Duration(rate.Increment) * time.Minute
+		return time.Duration(rate.Rounding.Increments(stay, increment)) * increment
+	}
+	return stay
+}
@@ -0,0 +1,445 @@
+package pricing
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"spotHero/app/model"
+	"time"
+)
+
+// Policy decides how the priced segments of a stay are combined into one price.
+type Policy string
+
+const (
+	// PolicyStrict sums the segment prices and rejects the stay if any part of it isn't covered by a rate.
+	PolicyStrict Policy = "strict"
+	// PolicySum sums the segment prices, uncovered parts of the stay are not charged.
+	PolicySum Policy = "sum"
+	// PolicyMax charges the highest segment price, uncovered parts of the stay are not charged.
+	PolicyMax Policy = "max"
+)
+
+// ErrUnavailable is returned when the stay can't be priced with the given rates, it's wrapped with the reason.
+var ErrUnavailable = errors.New("unavailable")
+
+// unavailable returns the ErrUnavailable wrapped with the reason.
+func unavailable(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrUnavailable, fmt.Sprintf(format, args...))
+}
+
+// ParsePolicy parse the policy name into a Policy.
+func ParsePolicy(name string) (Policy, error) {
+	switch policy := Policy(name); policy {
+	case PolicyStrict, PolicySum, PolicyMax:
+		return policy, nil
+	}
+	return "", fmt.Errorf("unknown pricing policy '%s'", name)
+}
+
+// Segment is a part of the stay covered by a single rate window, start & end are in the rate's time zone.
+// Override is the name of the calendar override applied on the window date, if any. Billed is the time charged
+// for the segment, rounded to the increments of the increment & tiered rates.
+type Segment struct {
+	Rate     model.Rate
+	Override string
+	Start    time.Time
+	End      time.Time
+	Billed   time.Duration
+	Price    int
+}
+
+// Gap is a part of the stay not covered by any rate window.
+type Gap struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Adjustment is a change applied on the sum of the segment prices to reach the price of the stay.
+type Adjustment struct {
+	Name   string
+	Amount int
+}
+
+// TaxLine is the tax or fee charged on top of the price of the stay.
+type TaxLine struct {
+	Name   string
+	Amount int
+}
+
+// Quote contains the currency of the amounts, the priced segments, the uncovered gaps, the adjustments and the combined price of a stay,
+// with the tax lines charged on the price and the total. The stay priced by a product has the product priced for the stay,
+// along with the rate segments it beats.
+type Quote struct {
+	Currency    model.Currency
+	Product     *model.Product
+	Segments    []Segment
+	Gaps        []Gap
+	Adjustments []Adjustment
+	Price       int
+	Taxes       []TaxLine
+	Total       int
+
+	// firstHour is the share of the segment prices billed for the first hour of the stay, before the caps.
+	firstHour int
+}
+
+// Subtotal returns the sum of the segment prices, before the adjustments.
+func (q *Quote) Subtotal() int {
+	subtotal := 0
+	for _, segment := range q.Segments {
+		subtotal += segment.Price
+	}
+	return subtotal
+}
+
+// adjust appends the adjustment and applies it on the price.
+func (q *Quote) adjust(name string, amount int) {
+	if amount == 0 {
+		return
+	}
+	q.Adjustments = append(q.Adjustments, Adjustment{Name: name, Amount: amount})
+	q.Price += amount
+}
+
+// Tariff contains the rates, caps, calendar overrides & products used to price a stay of the vehicle class,
+// with the adjusters & the promo code discount if any, and the tax rules charged on top of the price.
+// The prices are in the currency, USD if empty.
+type Tariff struct {
+	Currency  model.Currency
+	Rates     []model.Rate
+	Products  []model.Product
+	Caps      model.PriceCaps
+	Overrides []model.CalendarOverride
+	Vehicle   model.VehicleClass
+	Promo     *model.PromoCode
+	Adjusters []PricingAdjuster
+	Taxes     []model.TaxRule
+}
+
+// Engine prices a stay by splitting it into segments across days and rate windows.
+type Engine struct {
+	Policy Policy
+}
+
+// NewEngine returns the pricing engine for the given policy.
+func NewEngine(policy Policy) *Engine {
+	return &Engine{Policy: policy}
+}
+
+// window is the occurrence of a rate on a particular date.
+type window struct {
+	rate      model.Rate
+	rateIndex int
+	override  string
+	start     time.Time
+	end       time.Time
+}
+
+// Quote price the stay between start and end against the tariff rates in force at the start, the caps are applied on the combined price.
+// The cheapest product covering the stay is taken instead if it's cheaper, or if the rates can't price the stay.
+// The adjusters are then applied in order on the price, and the promo code discount last. The taxes are charged on the final price.
+// The fixed off promo code of another currency is the PromoError, the flat tax rule of another currency makes the stay unavailable.
+func (e *Engine) Quote(tariff Tariff, start, end time.Time) (*Quote, error) {
+	if end.Before(start) {
+		return nil, unavailable("end is before start")
+	}
+
+	if end.Sub(start) > MaxStay {
+		return nil, unavailable("stay is longer than %d days", MaxStay/(24*time.Hour))
+	}
+
+	// the fixed amounts of the promo code & the flat tax rules are only taken in the tariff currency
+	currency := tariff.Currency.OrDefault()
+	if promo := tariff.Promo; promo != nil && promo.Kind == model.PromoFixedOff && promo.Currency.OrDefault() != currency {
+		return nil, &model.PromoError{Code: promo.Code, Reason: fmt.Sprintf("is in %s, the facility prices are in %s", promo.Currency.OrDefault(), currency)}
+	}
+	for _, rule := range tariff.Taxes {
+		if rule.Kind == model.TaxFlat && rule.Currency.OrDefault() != currency {
+			return nil, unavailable("tax '%s' is in %s, the facility prices are in %s", rule.Name, rule.Currency.OrDefault(), currency)
+		}
+	}
+
+	quote, err := e.quoteRates(tariff, start, end)
+	if err != nil && !errors.Is(err, ErrUnavailable) {
+		return nil, err
+	}
+	product, productErr := cheapestProduct(tariff.Products, tariff.Overrides, tariff.Vehicle, start, end)
+	if productErr != nil {
+		return nil, productErr
+	}
+	if product != nil && (quote == nil || product.Price < quote.Price) {
+		// the rate segments are kept to show the subtotal the product beats, the caps adjustments were of the rate price
+		if quote == nil {
+			quote = &Quote{}
+		}
+		quote.Product, quote.Price, quote.Adjustments = product, product.Price, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	quote.Currency = currency
+
+	for _, adjuster := range tariff.Adjusters {
+		adjustment, err := adjuster.Adjust(quote, start, end)
+		if err != nil {
+			return nil, err
+		}
+		quote.adjust(adjustment.Name, adjustment.Amount)
+	}
+
+	if tariff.Promo != nil {
+		quote.adjust("promo "+tariff.Promo.Code, -e.discount(tariff, quote, start, end))
+	}
+
+	quote.Total = quote.Price
+	for _, rule := range tariff.Taxes {
+		tax := TaxLine{Name: rule.Name, Amount: rule.Charge(quote.Price)}
+		quote.Taxes = append(quote.Taxes, tax)
+		quote.Total += tax.Amount
+	}
+	return quote, nil
+}
+
+// quoteRates price the stay against the tariff rates in force at the start with the caps applied on the combined price.
+func (e *Engine) quoteRates(tariff Tariff, start, end time.Time) (*Quote, error) {
+	windows, err := rateWindows(inForce(tariff.Rates, start), tariff.Overrides, tariff.Vehicle, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	quote := &Quote{}
+	if start.Equal(end) {
+		// zero length stay, it only needs a window containing the instant.
+		if i := coveringWindow(windows, start, true); i >= 0 {
+			w := windows[i]
+			quote.Segments = append(quote.Segments, Segment{Rate: w.rate, Override: w.override, Start: start.In(w.start.Location()), End: end.In(w.start.Location()), Price: amount(w.rate, 0)})
+		}
+	}
+
+	// walking the stay, taking the highest priority window covering the cursor or skipping to the next window start.
+	// a flat window is charged once, even if a higher priority window splits it into several segments, while the
+	// increment & tiered rates are charged on their billed time so far over the stay, so that tiers aren't restarted.
+	billed := map[int]time.Duration{}
+	// the first hour of the stay is billed as per the rates too, the flat windows are pro-rated on their time over the stay.
+	firstHourEnd, flatFirstHour := start.Add(time.Hour), map[int]time.Duration{}
+	for cursor := start; cursor.Before(end); {
+		i, next := findWindow(windows, cursor, end)
+		if i < 0 {
+			quote.Gaps = append(quote.Gaps, Gap{Start: cursor, End: next})
+			cursor = next
+			continue
+		}
+
+		covering := windows[i]
+		key := i
+		if covering.rate.Kind == model.KindIncrement || covering.rate.Kind == model.KindTiered {
+			key = -1 - covering.rateIndex
+		}
+		before, isBilled := billed[key]
+		billed[key] = before + next.Sub(cursor)
+
+		price, segmentBilled := 0, billedTime(covering.rate, next.Sub(cursor))
+		if key != i {
+			price = amount(covering.rate, billed[key]) - amount(covering.rate, before)
+			segmentBilled = billedTime(covering.rate, billed[key]) - billedTime(covering.rate, before)
+		} else if !isBilled {
+			price = covering.rate.Price
+		}
+
+		if cursor.Before(firstHourEnd) {
+			inFirstHour := next.Sub(cursor)
+			if next.After(firstHourEnd) {
+				inFirstHour = firstHourEnd.Sub(cursor)
+			}
+			if key != i {
+				quote.firstHour += amount(covering.rate, before+inFirstHour) - amount(covering.rate, before)
+			} else {
+				flatFirstHour[i] += inFirstHour
+			}
+		}
+
+		loc := covering.start.Location()
+		quote.Segments = append(quote.Segments, Segment{Rate: covering.rate, Override: covering.override, Start: cursor.In(loc), End: next.In(loc), Billed: segmentBilled, Price: price})
+		cursor = next
+	}
+	for i, inFirstHour := range flatFirstHour {
+		quote.firstHour += int(math.Round(float64(windows[i].rate.Price) * float64(inFirstHour) / float64(billed[i])))
+	}
+
+	if err := e.combine(quote); err != nil {
+		return nil, err
+	}
+	applyCaps(quote, tariff.Caps, start)
+	return quote, nil
+}
+
+// combine the segment prices of the quote as per the engine policy.
+func (e *Engine) combine(quote *Quote) error {
+	if len(quote.Segments) == 0 {
+		return unavailable("no rate covers the stay")
+	}
+
+	quote.Price = quote.Subtotal()
+	switch e.Policy {
+	case PolicyStrict, PolicySum:
+		if e.Policy == PolicyStrict && len(quote.Gaps) > 0 {
+			gap := quote.Gaps[0]
+			return unavailable("no rate covers %s to %s", gap.Start.Format(time.RFC3339), gap.End.Format(time.RFC3339))
+		}
+	case PolicyMax:
+		highest := 0
+		for _, segment := range quote.Segments {
+			if segment.Price > highest {
+				highest = segment.Price
+			}
+		}
+		quote.adjust("max policy", highest-quote.Price)
+	default:
+		return fmt.Errorf("unknown pricing policy '%s'", e.Policy)
+	}
+	return nil
+}
+
+// rateWindows returns the window occurrences of the rates on every date touched by the stay,
+// the stay is converted into each rate's own time zone so that weekdays & times are matched on local wall-clock time.
+// The calendar overrides are checked first on every date: their rates replace the weekday rates, or the weekday rate
+// prices are multiplied. The window rate prices are then multiplied for the vehicle class.
+func rateWindows(rates []model.Rate, overrides []model.CalendarOverride, vehicle model.VehicleClass, start, end time.Time) ([]window, error) {
+	var windows []window
+	locations := map[string]*time.Location{}
+	addWindows := func(rate model.Rate, rateIndex int, onDate func(date time.Time) (model.Rate, string, bool)) error {
+		loc, isLoaded := locations[rate.Tz]
+		if !isLoaded {
+			var err error
+			if loc, err = time.LoadLocation(rate.Tz); err != nil {
+				return err
+			}
+			locations[rate.Tz] = loc
+		}
+
+		timeWindow := rate.Window()
+
+		localStart, localEnd := start.In(loc), end.In(loc)
+		lastDate := time.Date(localEnd.Year(), localEnd.Month(), localEnd.Day(), 0, 0, 0, 0, loc)
+		for date := time.Date(localStart.Year(), localStart.Month(), localStart.Day(), 0, 0, 0, 0, loc); !date.After(lastDate); date = date.AddDate(0, 0, 1) {
+			if !rate.CoversWeekday(date.Weekday()) {
+				continue
+			}
+			dateRate, override, isOn := onDate(date)
+			if !isOn {
+				continue
+			}
+			windows = append(windows, window{
+				rate:      dateRate.ForVehicle(vehicle),
+				rateIndex: rateIndex,
+				override:  override,
+				start:     timeWindow.Start.On(date),
+				end:       timeWindow.End.On(date),
+			})
+		}
+		return nil
+	}
+
+	for rateIndex, rate := range rates {
+		rate := rate
+		err := addWindows(rate, rateIndex, func(date time.Time) (model.Rate, string, bool) {
+			override := overrideOn(overrides, date)
+			switch {
+			case override == nil:
+				return rate, "", true
+			case len(override.Rates) > 0:
+				return rate, "", false
+			}
+			return override.Apply(rate), override.Name, true
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// the override rates are billed apart from the weekday rates
+	rateIndex := len(rates)
+	for _, override := range overrides {
+		override := override
+		for _, rate := range override.Rates {
+			rate := rate
+			err := addWindows(rate, rateIndex, func(date time.Time) (model.Rate, string, bool) {
+				return rate, override.Name, override.Covers(date)
+			})
+			if err != nil {
+				return nil, err
+			}
+			rateIndex++
+		}
+	}
+	return windows, nil
+}
+
+// inForce returns the rates in force at the stay start, the version of a rate window taking effect the latest.
+func inForce(rates []model.Rate, start time.Time) []model.Rate {
+	var effective []model.Rate
+	for _, rate := range rates {
+		if !rate.InForce(start) {
+			continue
+		}
+		superseded := false
+		for _, other := range rates {
+			if other.SameWindow(rate) && other.InForce(start) && other.EffectiveFrom != nil &&
+				(rate.EffectiveFrom == nil || other.EffectiveFrom.After(*rate.EffectiveFrom)) {
+				superseded = true
+				break
+			}
+		}
+		if !superseded {
+			effective = append(effective, rate)
+		}
+	}
+	return effective
+}
+
+// overrideOn returns the calendar override covering the local date, or nil.
+func overrideOn(overrides []model.CalendarOverride, date time.Time) *model.CalendarOverride {
+	for i := range overrides {
+		if overrides[i].Covers(date) {
+			return &overrides[i]
+		}
+	}
+	return nil
+}
+
+// coveringWindow returns the index of the highest priority window covering the instant, or -1 if none covers it.
+// With inclusiveEnd the window also covers its end instant.
+func coveringWindow(windows []window, instant time.Time, inclusiveEnd bool) int {
+	found := -1
+	for i, w := range windows {
+		if instant.Before(w.start) || instant.After(w.end) || (!inclusiveEnd && instant.Equal(w.end)) {
+			continue
+		}
+		if found < 0 || w.rate.Priority > windows[found].rate.Priority {
+			found = i
+		}
+	}
+	return found
+}
+
+// findWindow returns the index of the highest priority window covering the cursor and the time until it covers the stay,
+// cut short by any higher priority window starting in between. If none covers the cursor, returns -1 and the start of
+// the next window (or end).
+func findWindow(windows []window, cursor, end time.Time) (int, time.Time) {
+	found := coveringWindow(windows, cursor, false)
+
+	next := end
+	if found >= 0 && windows[found].end.Before(next) {
+		next = windows[found].end
+	}
+	for _, w := range windows {
+		if found >= 0 && w.rate.Priority <= windows[found].rate.Priority {
+			continue
+		}
+		if w.start.After(cursor) && w.start.Before(next) {
+			next = w.start
+		}
+	}
+	return found, next
+}
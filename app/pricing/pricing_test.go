@@ -0,0 +1,585 @@
+package pricing
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+	"spotHero/app/model"
+	"testing"
+	"time"
+)
+
+// Suite has the rates & location used for pricing
+type Suite struct {
+	suite.Suite
+	rates []model.Rate
+	loc   *time.Location
+}
+
+func (s *Suite) SetupSuite() {
+	loc, err := time.LoadLocation("America/Chicago")
+	require.NoError(s.T(), err)
+	s.loc = loc
+
+	s.rates = []model.Rate{
+		s.rate("mon,tues,thurs", "0900-2100", "America/Chicago", 1500),
+		s.rate("fri,sat,sun", "0900-2100", "America/Chicago", 2000),
+		s.rate("wed", "0600-1800", "America/Chicago", 1750),
+		s.rate("mon,wed,sat", "0100-0500", "America/Chicago", 1000),
+		s.rate("sun,tues", "0100-0700", "America/Chicago", 925),
+		s.rate("fri", "2100-2400", "America/Chicago", 500),
+	}
+}
+
+func TestInit(t *testing.T) {
+	suite.Run(t, new(Suite))
+}
+
+// at returns the time in the suite location on July 2015.
+func (s *Suite) at(day, hour int) time.Time {
+	return time.Date(2015, time.July, day, hour, 0, 0, 0, s.loc)
+}
+
+// rate returns the rate parsed from the wire format values.
+func (s *Suite) rate(days, times, tz string, price int) model.Rate {
+	rate, err := model.NewRate(days, times, tz, price)
+	require.NoError(s.T(), err)
+	return rate
+}
+
+// TestParsePolicy test the known & unknown policy names.
+func (s *Suite) TestParsePolicy() {
+	policy, err := ParsePolicy("max")
+	assert.NoError(s.T(), err)
+	assert.Equal(s.T(), PolicyMax, policy)
+
+	_, err = ParsePolicy("min")
+	assert.Error(s.T(), err)
+	assert.Equal(s.T(), "unknown pricing policy 'min'", err.Error())
+}
+
+// TestQuoteSingleWindow return the window price for a stay inside one window.
+func (s *Suite) TestQuoteSingleWindow() {
+	quote, err := NewEngine(PolicyStrict).Quote(Tariff{Rates: s.rates}, s.at(1, 7), s.at(1, 12))
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 1750, quote.Price)
+	assert.Len(s.T(), quote.Segments, 1)
+	assert.Empty(s.T(), quote.Gaps)
+}
+
+// TestQuoteOvernightStrict sums the windows of an overnight stay without gaps.
+func (s *Suite) TestQuoteOvernightStrict() {
+	// fri 20:00 -> sat 04:00: fri 0900-2100, fri 2100-2400, sat 0100-0500 with a gap between 00:00-01:00
+	_, err := NewEngine(PolicyStrict).Quote(Tariff{Rates: s.rates}, s.at(3, 20), s.at(4, 4))
+	assert.ErrorIs(s.T(), err, ErrUnavailable)
+	assert.Equal(s.T(), "unavailable: no rate covers 2015-07-04T00:00:00-05:00 to 2015-07-04T01:00:00-05:00", err.Error())
+
+	// fri 20:00 -> sat 00:00
+	quote, err := NewEngine(PolicyStrict).Quote(Tariff{Rates: s.rates}, s.at(3, 20), s.at(4, 0))
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 2500, quote.Price)
+	assert.Len(s.T(), quote.Segments, 2)
+	assert.Equal(s.T(), s.at(3, 21), quote.Segments[0].End)
+	assert.Equal(s.T(), s.at(3, 21), quote.Segments[1].Start)
+}
+
+// TestQuoteOvernightSum sums the covered windows of an overnight stay and ignores the gaps.
+func (s *Suite) TestQuoteOvernightSum() {
+	quote, err := NewEngine(PolicySum).Quote(Tariff{Rates: s.rates}, s.at(3, 20), s.at(4, 4))
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 3500, quote.Price)
+	assert.Len(s.T(), quote.Segments, 3)
+	assert.Equal(s.T(), []Gap{{Start: s.at(4, 0), End: s.at(4, 1)}}, quote.Gaps)
+}
+
+// TestQuoteOvernightMax charges the highest window of an overnight stay.
+func (s *Suite) TestQuoteOvernightMax() {
+	quote, err := NewEngine(PolicyMax).Quote(Tariff{Rates: s.rates}, s.at(3, 20), s.at(4, 4))
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 2000, quote.Price)
+	assert.Equal(s.T(), 3500, quote.Subtotal())
+	assert.Equal(s.T(), []Adjustment{{Name: "max policy", Amount: -1500}}, quote.Adjustments)
+}
+
+// TestQuoteNoWindow return unavailable when no window covers the stay.
+func (s *Suite) TestQuoteNoWindow() {
+	_, err := NewEngine(PolicySum).Quote(Tariff{Rates: s.rates}, s.at(1, 19), s.at(1, 20))
+	assert.ErrorIs(s.T(), err, ErrUnavailable)
+}
+
+// TestQuoteZeroLength price the window containing the instant.
+func (s *Suite) TestQuoteZeroLength() {
+	quote, err := NewEngine(PolicyStrict).Quote(Tariff{Rates: s.rates}, s.at(1, 18), s.at(1, 18))
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 1750, quote.Price)
+}
+
+// TestQuoteEndBeforeStart return unavailable for the reversed stay.
+func (s *Suite) TestQuoteEndBeforeStart() {
+	_, err := NewEngine(PolicyStrict).Quote(Tariff{Rates: s.rates}, s.at(1, 12), s.at(1, 7))
+	assert.ErrorIs(s.T(), err, ErrUnavailable)
+}
+
+// TestQuoteRateTimeZone match the stay on the rate's local wall-clock time.
+func (s *Suite) TestQuoteRateTimeZone() {
+	rates := []model.Rate{
+		s.rate("wed", "0900-1200", "America/New_York", 3000),
+		s.rate("wed", "0900-1200", "Asia/Kolkata", 4000),
+	}
+
+	// wed 08:00-10:00 Chicago is wed 09:00-11:00 New York
+	quote, err := NewEngine(PolicyStrict).Quote(Tariff{Rates: rates}, s.at(1, 8), s.at(1, 10))
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 3000, quote.Price)
+	assert.Equal(s.T(), 9, quote.Segments[0].Start.Hour())
+	assert.Equal(s.T(), "America/New_York", quote.Segments[0].Start.Location().String())
+
+	// wed 10:00-12:00 +05:30 is wed 09:30-11:30 Kolkata, even though it's tue night in Chicago
+	kolkata := time.FixedZone("+05:30", 5*60*60+30*60)
+	quote, err = NewEngine(PolicyStrict).Quote(Tariff{Rates: rates}, time.Date(2015, time.July, 1, 10, 0, 0, 0, kolkata), time.Date(2015, time.July, 1, 12, 0, 0, 0, kolkata))
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 4000, quote.Price)
+}
+
+// TestQuoteInvalidTimeZone return error for the rate with unknown time zone.
+func (s *Suite) TestQuoteInvalidTimeZone() {
+	rate := s.rate("wed", "0900-1200", "America/Chicago", 3000)
+	rate.Tz = "Mars/Olympus"
+	rates := []model.Rate{rate}
+	_, err := NewEngine(PolicyStrict).Quote(Tariff{Rates: rates}, s.at(1, 9), s.at(1, 10))
+	assert.Error(s.T(), err)
+}
+
+// TestQuoteMinuteWindow match the stay against minute precision windows.
+func (s *Suite) TestQuoteMinuteWindow() {
+	rates := []model.Rate{s.rate("wed", "0630-1815", "America/Chicago", 1200)}
+	start := time.Date(2015, time.July, 1, 6, 30, 0, 0, s.loc)
+
+	quote, err := NewEngine(PolicyStrict).Quote(Tariff{Rates: rates}, start, start.Add(11*time.Hour+45*time.Minute))
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 1200, quote.Price)
+
+	_, err = NewEngine(PolicyStrict).Quote(Tariff{Rates: rates}, start, start.Add(11*time.Hour+46*time.Minute))
+	assert.ErrorIs(s.T(), err, ErrUnavailable)
+
+	_, err = NewEngine(PolicyStrict).Quote(Tariff{Rates: rates}, start.Add(-time.Minute), start.Add(time.Hour))
+	assert.ErrorIs(s.T(), err, ErrUnavailable)
+}
+
+// TestQuoteMidnightWindow cover the whole day with "0000-2400" window.
+func (s *Suite) TestQuoteMidnightWindow() {
+	rates := []model.Rate{s.rate("fri,sat", "0000-2400", "America/Chicago", 100)}
+	quote, err := NewEngine(PolicyStrict).Quote(Tariff{Rates: rates}, s.at(3, 22), s.at(4, 8))
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 200, quote.Price)
+	assert.Equal(s.T(), s.at(4, 0), quote.Segments[0].End)
+}
+
+// TestQuotePriority price the overlapping part of the stay with the higher priority rate, charging each window once.
+func (s *Suite) TestQuotePriority() {
+	allDay := s.rate("wed", "0000-2400", "America/Chicago", 3000)
+	lunch := s.rate("wed", "1200-1300", "America/Chicago", 500)
+	lunch.Priority = 1
+	rates := []model.Rate{allDay, lunch}
+
+	quote, err := NewEngine(PolicyStrict).Quote(Tariff{Rates: rates}, s.at(1, 11), s.at(1, 14))
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 3500, quote.Price)
+	require.Len(s.T(), quote.Segments, 3)
+	assert.Equal(s.T(), 500, quote.Segments[1].Price)
+	assert.Equal(s.T(), 0, quote.Segments[2].Price)
+
+	quote, err = NewEngine(PolicyStrict).Quote(Tariff{Rates: rates}, s.at(1, 12), s.at(1, 13))
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 500, quote.Price)
+}
+
+// TestQuoteCaps apply the daily max, weekly max & minimum charge on the stay price.
+func (s *Suite) TestQuoteCaps() {
+	rates := []model.Rate{s.rate("mon,tues,wed,thurs,fri,sat,sun", "0000-2400", "America/Chicago", 2500)}
+
+	// wed 00:00 -> wed 00:00 next week is 7 windows, one per 24 hours
+	quote, err := NewEngine(PolicyStrict).Quote(Tariff{Rates: rates, Caps: model.PriceCaps{DailyMax: 2000, WeeklyMax: 12000}}, s.at(1, 0), s.at(8, 0))
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 12000, quote.Price)
+	assert.Equal(s.T(), []Adjustment{{Name: "daily max", Amount: -3500}, {Name: "weekly max", Amount: -2000}}, quote.Adjustments)
+
+	quote, err = NewEngine(PolicyStrict).Quote(Tariff{Rates: rates, Caps: model.PriceCaps{MinCharge: 3000}}, s.at(1, 10), s.at(1, 11))
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 3000, quote.Price)
+	assert.Equal(s.T(), []Adjustment{{Name: "minimum charge", Amount: 500}}, quote.Adjustments)
+
+	// the max policy price is already under the daily max
+	quote, err = NewEngine(PolicyMax).Quote(Tariff{Rates: rates, Caps: model.PriceCaps{DailyMax: 3000}}, s.at(1, 10), s.at(2, 11))
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 2500, quote.Price)
+	assert.Equal(s.T(), []Adjustment{{Name: "max policy", Amount: -2500}}, quote.Adjustments)
+}
+
+// TestQuoteIncrement charge the increment rate for every billed increment of the stay, as per the rounding.
+func (s *Suite) TestQuoteIncrement() {
+	rate := s.rate("wed", "0600-1800", "America/Chicago", 200)
+	rate.Kind, rate.Increment, rate.Rounding = model.KindIncrement, 15, model.RoundUp
+	start := s.at(1, 9)
+
+	quote, err := NewEngine(PolicyStrict).Quote(Tariff{Rates: []model.Rate{rate}}, start, start.Add(time.Hour+time.Minute))
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 1000, quote.Price)
+	// the 61 minutes are billed as five 15 minute increments
+	assert.Equal(s.T(), 75*time.Minute, quote.Segments[0].Billed)
+
+	quote, err = NewEngine(PolicyStrict).Quote(Tariff{Rates: []model.Rate{rate}}, start, start.Add(10*time.Minute))
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 200, quote.Price)
+	assert.Equal(s.T(), 15*time.Minute, quote.Segments[0].Billed)
+
+	rate.Rounding = model.RoundDown
+	quote, err = NewEngine(PolicyStrict).Quote(Tariff{Rates: []model.Rate{rate}}, start, start.Add(time.Hour+time.Minute))
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 800, quote.Price)
+
+	// zero length stay isn't billed any increment
+	quote, err = NewEngine(PolicyStrict).Quote(Tariff{Rates: []model.Rate{rate}}, start, start)
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 0, quote.Price)
+}
+
+// TestQuoteTiered charge the tier prices first & the rate price after, across the windows split by a higher priority rate.
+func (s *Suite) TestQuoteTiered() {
+	hourly := s.rate("wed", "0000-2400", "America/Chicago", 300)
+	hourly.Kind, hourly.Increment, hourly.Rounding = model.KindTiered, 60, model.RoundUp
+	hourly.Tiers = model.Tiers{{Minutes: 60, Price: 500}}
+
+	quote, err := NewEngine(PolicyStrict).Quote(Tariff{Rates: []model.Rate{hourly}}, s.at(1, 9), s.at(1, 12).Add(time.Minute))
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 500+3*300, quote.Price)
+
+	// the lunch rate splits the stay, the hourly tiers continue after it
+	lunch := s.rate("wed", "1200-1300", "America/Chicago", 700)
+	lunch.Priority = 1
+	quote, err = NewEngine(PolicyStrict).Quote(Tariff{Rates: []model.Rate{hourly, lunch}}, s.at(1, 11), s.at(1, 15))
+	require.NoError(s.T(), err)
+	require.Len(s.T(), quote.Segments, 3)
+	assert.Equal(s.T(), 500, quote.Segments[0].Price)
+	assert.Equal(s.T(), 700, quote.Segments[1].Price)
+	assert.Equal(s.T(), 600, quote.Segments[2].Price)
+	assert.Equal(s.T(), 1800, quote.Price)
+}
+
+// TestQuoteCalendarOverride price the override dates with the override rates or the multiplied weekday rates.
+func (s *Suite) TestQuoteCalendarOverride() {
+	event := s.rate("mon,tues,wed,thurs,fri,sat,sun", "1700-2400", "America/Chicago", 4000)
+	overrides := []model.CalendarOverride{
+		{Name: "July 4th", StartDate: "2015-07-04", EndDate: "2015-07-04", Multiplier: 1.5},
+		{Name: "Stadium", StartDate: "2015-07-10", EndDate: "2015-07-10", Rates: model.OverrideRates{event}},
+	}
+	tariff := Tariff{Rates: s.rates, Overrides: overrides}
+
+	quote, err := NewEngine(PolicyStrict).Quote(tariff, s.at(4, 10), s.at(4, 15))
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 3000, quote.Price)
+	assert.Equal(s.T(), "July 4th", quote.Segments[0].Override)
+
+	// the weekday rate isn't matched on the event date, only the event rate
+	quote, err = NewEngine(PolicyStrict).Quote(tariff, s.at(10, 18), s.at(10, 22))
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 4000, quote.Price)
+	assert.Equal(s.T(), "Stadium", quote.Segments[0].Override)
+
+	_, err = NewEngine(PolicyStrict).Quote(tariff, s.at(10, 10), s.at(10, 15))
+	assert.ErrorIs(s.T(), err, ErrUnavailable)
+
+	// a day after the event, the weekday rate is back
+	quote, err = NewEngine(PolicyStrict).Quote(tariff, s.at(11, 10), s.at(11, 15))
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 2000, quote.Price)
+}
+
+// TestQuoteEffectiveRates price the stay with the version of the rate window in force at the start.
+func (s *Suite) TestQuoteEffectiveRates() {
+	current := s.rate("wed", "0600-1800", "America/Chicago", 1750)
+	raised := s.rate("wed", "0600-1800", "America/Chicago", 1900)
+	raisedFrom := s.at(8, 0)
+	raised.EffectiveFrom = &raisedFrom
+	tariff := Tariff{Rates: []model.Rate{current, raised}}
+
+	quote, err := NewEngine(PolicyStrict).Quote(tariff, s.at(1, 7), s.at(1, 12))
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 1750, quote.Price)
+
+	quote, err = NewEngine(PolicyStrict).Quote(tariff, s.at(8, 7), s.at(8, 12))
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 1900, quote.Price)
+
+	// the rate expired before the stay
+	raisedTo := s.at(15, 0)
+	tariff.Rates[1].EffectiveTo = &raisedTo
+	tariff.Rates[0].EffectiveTo = &raisedFrom
+	_, err = NewEngine(PolicyStrict).Quote(tariff, s.at(15, 7), s.at(15, 12))
+	assert.ErrorIs(s.T(), err, ErrUnavailable)
+}
+
+// TestQuoteVehicle price the stay with the rate prices multiplied for the vehicle class, the class without a multiplier is charged the rate price.
+func (s *Suite) TestQuoteVehicle() {
+	wednesday := s.rate("wed", "0600-1800", "America/Chicago", 1750)
+	wednesday.Vehicles = model.VehicleMultipliers{model.VehicleOversize: 1.5, model.VehicleMotorcycle: 0.5}
+	overrides := []model.CalendarOverride{{Name: "Sale", StartDate: "2015-07-08", EndDate: "2015-07-08", Multiplier: 0.8}}
+	tariff := Tariff{Rates: []model.Rate{wednesday}, Overrides: overrides}
+
+	for vehicle, price := range map[model.VehicleClass]int{
+		model.VehicleStandard:   1750,
+		model.VehicleCompact:    1750,
+		model.VehicleOversize:   2625,
+		model.VehicleMotorcycle: 875,
+		"":                      1750,
+	} {
+		tariff.Vehicle = vehicle
+		quote, err := NewEngine(PolicyStrict).Quote(tariff, s.at(1, 7), s.at(1, 12))
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), price, quote.Price, vehicle)
+	}
+
+	// the vehicle multiplier is applied on the calendar override price
+	tariff.Vehicle = model.VehicleOversize
+	quote, err := NewEngine(PolicyStrict).Quote(tariff, s.at(8, 7), s.at(8, 12))
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 2100, quote.Price)
+}
+
+// TestQuotePromo take the promo code discount off the price, down to free.
+func (s *Suite) TestQuotePromo() {
+	hourly := s.rate("wed", "0000-2400", "America/Chicago", 300)
+	hourly.Kind, hourly.Increment, hourly.Rounding = model.KindTiered, 60, model.RoundUp
+	hourly.Tiers = model.Tiers{{Minutes: 60, Price: 500}}
+	tariff := Tariff{Rates: []model.Rate{hourly}}
+
+	for _, tt := range []struct {
+		promo model.PromoCode
+		price int
+	}{
+		{model.PromoCode{Code: "TENOFF", Kind: model.PromoPercentOff, Percent: 10}, 1260},
+		{model.PromoCode{Code: "FIVE", Kind: model.PromoFixedOff, Amount: 500}, 900},
+		{model.PromoCode{Code: "HUGE", Kind: model.PromoFixedOff, Amount: 5000}, 0},
+		{model.PromoCode{Code: "FIRST", Kind: model.PromoFreeFirstHour}, 900},
+	} {
+		promo := tt.promo
+		tariff.Promo = &promo
+		quote, err := NewEngine(PolicyStrict).Quote(tariff, s.at(1, 9), s.at(1, 12).Add(time.Minute))
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), tt.price, quote.Price, promo.Code)
+		assert.Equal(s.T(), []Adjustment{{Name: "promo " + promo.Code, Amount: tt.price - 1400}}, quote.Adjustments)
+	}
+
+	// the stay within the first hour is free
+	quote, err := NewEngine(PolicyStrict).Quote(tariff, s.at(1, 9), s.at(1, 9).Add(45*time.Minute))
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 0, quote.Price)
+
+	// the min charge isn't taken as the first hour price
+	tariff.Caps = model.PriceCaps{MinCharge: 2000}
+	quote, err = NewEngine(PolicyStrict).Quote(tariff, s.at(1, 9), s.at(1, 12).Add(time.Minute))
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 1500, quote.Price)
+
+	// the first hour of the flat rate is its share of the flat price, the stay isn't free
+	day := s.rate("wed", "0600-1800", "America/Chicago", 2000)
+	tariff = Tariff{Rates: []model.Rate{day}, Promo: &model.PromoCode{Code: "FIRST", Kind: model.PromoFreeFirstHour}}
+	quote, err = NewEngine(PolicyStrict).Quote(tariff, s.at(1, 8), s.at(1, 13))
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 1600, quote.Price)
+	assert.Equal(s.T(), []Adjustment{{Name: "promo FIRST", Amount: -400}}, quote.Adjustments)
+}
+
+// TestQuoteCurrency take the fixed amounts of the promo code & the flat tax rules only in the tariff currency, USD by default.
+func (s *Suite) TestQuoteCurrency() {
+	day := s.rate("wed", "0600-1800", "America/Chicago", 2000)
+	tariff := Tariff{Rates: []model.Rate{day}, Promo: &model.PromoCode{Code: "FIVE", Kind: model.PromoFixedOff, Amount: 500},
+		Taxes: []model.TaxRule{{Name: "Service fee", Kind: model.TaxFlat, Amount: 199, Currency: "USD"}}}
+
+	quote, err := NewEngine(PolicyStrict).Quote(tariff, s.at(1, 8), s.at(1, 16))
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), model.Currency("USD"), quote.Currency)
+	assert.Equal(s.T(), 1699, quote.Total)
+
+	tariff.Currency = "EUR"
+	_, err = NewEngine(PolicyStrict).Quote(tariff, s.at(1, 8), s.at(1, 16))
+	var promoErr *model.PromoError
+	require.ErrorAs(s.T(), err, &promoErr)
+	assert.EqualError(s.T(), err, "promo code 'FIVE' is in USD, the facility prices are in EUR")
+
+	tariff.Promo = &model.PromoCode{Code: "TENOFF", Kind: model.PromoPercentOff, Percent: 10}
+	_, err = NewEngine(PolicyStrict).Quote(tariff, s.at(1, 8), s.at(1, 16))
+	assert.ErrorIs(s.T(), err, ErrUnavailable)
+	assert.EqualError(s.T(), err, "unavailable: tax 'Service fee' is in USD, the facility prices are in EUR")
+
+	tariff.Taxes = []model.TaxRule{{Name: "City tax", Kind: model.TaxPercent, BasisPoints: 1000}, {Name: "Service fee", Kind: model.TaxFlat, Amount: 150, Currency: "EUR"}}
+	quote, err = NewEngine(PolicyStrict).Quote(tariff, s.at(1, 8), s.at(1, 16))
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), model.Currency("EUR"), quote.Currency)
+	assert.Equal(s.T(), 2130, quote.Total)
+}
+
+// TestQuoteProduct take the cheapest of the products covering the stay & the rates, even when the rates can't price the stay.
+func (s *Suite) TestQuoteProduct() {
+	day := s.rate("wed", "0600-1800", "America/Chicago", 2500)
+	earlyBird := model.Product{Name: "Early bird", Days: model.AllWeekdays, EntryStart: 5 * 60, EntryEnd: 9 * 60, ExitStart: 15 * 60, ExitEnd: 20 * 60, Tz: "America/Chicago", Price: 1500}
+	cheaper := earlyBird
+	cheaper.Name, cheaper.Price, cheaper.MaxMinutes = "Short early bird", 1200, 8*60
+	tariff := Tariff{Rates: []model.Rate{day}, Products: []model.Product{earlyBird, cheaper}}
+
+	quote, err := NewEngine(PolicyStrict).Quote(tariff, s.at(1, 8), s.at(1, 16))
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 1200, quote.Price)
+	assert.Equal(s.T(), "Short early bird", quote.Product.Name)
+	assert.Equal(s.T(), 2500, quote.Subtotal())
+
+	// the rates can't price the stay past 18:00, the early bird does
+	quote, err = NewEngine(PolicyStrict).Quote(tariff, s.at(1, 8), s.at(1, 19))
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 1500, quote.Price)
+	assert.Equal(s.T(), "Early bird", quote.Product.Name)
+	assert.Empty(s.T(), quote.Segments)
+
+	// the rate is cheaper than the products
+	day.Price = 1000
+	tariff.Rates = []model.Rate{day}
+	quote, err = NewEngine(PolicyStrict).Quote(tariff, s.at(1, 8), s.at(1, 16))
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 1000, quote.Price)
+	assert.Nil(s.T(), quote.Product)
+
+	// the promo code discount is taken off the product price
+	tariff.Rates[0].Price = 2500
+	tariff.Promo = &model.PromoCode{Code: "TENOFF", Kind: model.PromoPercentOff, Percent: 10}
+	quote, err = NewEngine(PolicyStrict).Quote(tariff, s.at(1, 8), s.at(1, 16))
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 1080, quote.Price)
+
+	// no product covers the stay entered late, the rates still can't price it
+	_, err = NewEngine(PolicyStrict).Quote(tariff, s.at(1, 10), s.at(1, 19))
+	assert.ErrorIs(s.T(), err, ErrUnavailable)
+}
+
+// TestQuoteProductOverride multiply the product price on the override dates, the products aren't sold on the dates of the override rates.
+func (s *Suite) TestQuoteProductOverride() {
+	day := s.rate("wed", "0600-1800", "America/Chicago", 2500)
+	event := s.rate("wed", "0600-2000", "America/Chicago", 4000)
+	earlyBird := model.Product{Name: "Early bird", Days: model.AllWeekdays, EntryStart: 5 * 60, EntryEnd: 9 * 60, ExitStart: 15 * 60, ExitEnd: 20 * 60, Tz: "America/Chicago", Price: 1500}
+	overrides := []model.CalendarOverride{
+		{Name: "Busy day", StartDate: "2015-07-08", EndDate: "2015-07-08", Multiplier: 1.5},
+		{Name: "Stadium", StartDate: "2015-07-15", EndDate: "2015-07-15", Rates: model.OverrideRates{event}},
+	}
+	tariff := Tariff{Rates: []model.Rate{day}, Products: []model.Product{earlyBird}, Overrides: overrides}
+
+	quote, err := NewEngine(PolicyStrict).Quote(tariff, s.at(8, 8), s.at(8, 16))
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 2250, quote.Price)
+	assert.Equal(s.T(), 2250, quote.Product.Price)
+	assert.Equal(s.T(), 3750, quote.Subtotal())
+
+	quote, err = NewEngine(PolicyStrict).Quote(tariff, s.at(15, 8), s.at(15, 16))
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 4000, quote.Price)
+	assert.Nil(s.T(), quote.Product)
+}
+
+// TestQuoteProductVehicle multiply the product price for the vehicle class, the class without a multiplier is charged the product price.
+func (s *Suite) TestQuoteProductVehicle() {
+	day := s.rate("wed", "0600-1800", "America/Chicago", 2500)
+	day.Vehicles = model.VehicleMultipliers{model.VehicleOversize: 2, model.VehicleMotorcycle: 0.5}
+	earlyBird := model.Product{Name: "Early bird", Days: model.AllWeekdays, EntryStart: 5 * 60, EntryEnd: 9 * 60, ExitStart: 15 * 60, ExitEnd: 20 * 60, Tz: "America/Chicago", Price: 1500,
+		Vehicles: model.VehicleMultipliers{model.VehicleOversize: 1.5}}
+	overrides := []model.CalendarOverride{{Name: "Sale", StartDate: "2015-07-08", EndDate: "2015-07-08", Multiplier: 0.8}}
+	tariff := Tariff{Rates: []model.Rate{day}, Products: []model.Product{earlyBird}, Overrides: overrides}
+
+	for vehicle, price := range map[model.VehicleClass]int{
+		model.VehicleStandard:   1500,
+		model.VehicleOversize:   2250,
+		model.VehicleMotorcycle: 1250,
+	} {
+		tariff.Vehicle = vehicle
+		quote, err := NewEngine(PolicyStrict).Quote(tariff, s.at(1, 8), s.at(1, 16))
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), price, quote.Price, vehicle)
+	}
+
+	// the vehicle multiplier is applied on the calendar override price
+	tariff.Vehicle = model.VehicleOversize
+	quote, err := NewEngine(PolicyStrict).Quote(tariff, s.at(8, 8), s.at(8, 16))
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 1800, quote.Price)
+	assert.Equal(s.T(), "Early bird", quote.Product.Name)
+}
+
+// TestQuoteOccupancySurge multiply the price by the surge band of the occupancy before the promo code discount, unless the surge is off.
+func (s *Suite) TestQuoteOccupancySurge() {
+	day := s.rate("wed", "0600-1800", "America/Chicago", 2000)
+	occupancy := 0
+	surge := OccupancySurge{
+		Surge:    model.Surge{Enabled: true, Bands: model.SurgeBands{{Occupancy: 0, Multiplier: 0.9}, {Occupancy: 50, Multiplier: 1}, {Occupancy: 90, Multiplier: 1.5}}},
+		Capacity: 10,
+		Occupancy: func(start, end time.Time) (int, error) {
+			return occupancy, nil
+		},
+	}
+	tariff := Tariff{Rates: []model.Rate{day}, Adjusters: []PricingAdjuster{surge}}
+
+	for _, tt := range []struct {
+		reserved int
+		price    int
+	}{
+		{0, 1800},
+		{5, 2000},
+		{10, 3000},
+		{9, 3000},
+	} {
+		occupancy = tt.reserved
+		quote, err := NewEngine(PolicyStrict).Quote(tariff, s.at(1, 8), s.at(1, 16))
+		require.NoError(s.T(), err)
+		assert.Equal(s.T(), tt.price, quote.Price, tt.reserved)
+	}
+
+	// the 90% occupancy band
+	occupancy = 9
+
+	quote, err := NewEngine(PolicyStrict).Quote(tariff, s.at(1, 8), s.at(1, 16))
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), []Adjustment{{Name: "occupancy surge x1.5", Amount: 1000}}, quote.Adjustments)
+
+	// the promo code discount is taken off the surged price
+	tariff.Promo = &model.PromoCode{Code: "FIVE", Kind: model.PromoFixedOff, Amount: 500}
+	quote, err = NewEngine(PolicyStrict).Quote(tariff, s.at(1, 8), s.at(1, 16))
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 2500, quote.Price)
+
+	// the kill switch
+	surge.Surge.Enabled = false
+	tariff.Adjusters, tariff.Promo = []PricingAdjuster{surge}, nil
+	quote, err = NewEngine(PolicyStrict).Quote(tariff, s.at(1, 8), s.at(1, 16))
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 2000, quote.Price)
+	assert.Empty(s.T(), quote.Adjustments)
+}
+
+// TestQuoteTaxes charge the tax lines on the discounted price, the total is the price with the taxes.
+func (s *Suite) TestQuoteTaxes() {
+	day := s.rate("wed", "0600-1800", "America/Chicago", 2000)
+	tariff := Tariff{
+		Rates: []model.Rate{day},
+		Promo: &model.PromoCode{Code: "FIVE", Kind: model.PromoFixedOff, Amount: 500},
+		Taxes: []model.TaxRule{{Name: "Parking tax", Kind: model.TaxPercent, BasisPoints: 2375}, {Name: "Service fee", Kind: model.TaxFlat, Amount: 199}},
+	}
+
+	quote, err := NewEngine(PolicyStrict).Quote(tariff, s.at(1, 8), s.at(1, 16))
+	require.NoError(s.T(), err)
+	assert.Equal(s.T(), 1500, quote.Price)
+	assert.Equal(s.T(), []TaxLine{{Name: "Parking tax", Amount: 356}, {Name: "Service fee", Amount: 199}}, quote.Taxes)
+	assert.Equal(s.T(), 2055, quote.Total)
+
+	// without tax rules the total is the price
+	tariff.Taxes = nil
+	quote, err = NewEngine(PolicyStrict).Quote(tariff, s.at(1, 8), s.at(1, 16))
+	require.NoError(s.T(), err)
+	assert.Empty(s.T(), quote.Taxes)
+	assert.Equal(s.T(), 1500, quote.Total)
+}
@@ -0,0 +1,41 @@
+package pricing
+
+import (
+	"spotHero/app/model"
+	"time"
+)
+
+// cheapestProduct returns the cheapest product covering the stay in its own time zone, or nil if none covers it.
+// The product price is multiplied by the calendar override of the stay date, then for the vehicle class like the rates.
+// The products aren't sold on the dates of the overrides with their own rates, these rates replace the whole tariff.
+func cheapestProduct(products []model.Product, overrides []model.CalendarOverride, vehicle model.VehicleClass, start, end time.Time) (*model.Product, error) {
+	var cheapest *model.Product
+	locations := map[string]*time.Location{}
+	for _, product := range products {
+		loc, isLoaded := locations[product.Tz]
+		if !isLoaded {
+			var err error
+			if loc, err = time.LoadLocation(product.Tz); err != nil {
+				return nil, err
+			}
+			locations[product.Tz] = loc
+		}
+
+		if !product.Covers(start, end, loc) {
+			continue
+		}
+		localStart := start.In(loc)
+		if override := overrideOn(overrides, time.Date(localStart.Year(), localStart.Month(), localStart.Day(), 0, 0, 0, 0, loc)); override != nil {
+			if len(override.Rates) > 0 {
+				continue
+			}
+			product = product.Scale(override.Multiplier)
+		}
+		product = product.ForVehicle(vehicle)
+		if cheapest == nil || product.Price < cheapest.Price {
+			priced := product
+			cheapest = &priced
+		}
+	}
+	return cheapest, nil
+}
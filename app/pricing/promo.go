@@ -0,0 +1,36 @@
+package pricing
+
+import (
+	"math"
+	"spotHero/app/model"
+	"time"
+)
+
+// discount returns the promo code discount of the quoted price, the price of the first hour of the stay is its share of the
+// billed segments, or of the product price pro-rated on the stay. The discount is at most the price.
+func (e *Engine) discount(tariff Tariff, quote *Quote, start, end time.Time) int {
+	promo := tariff.Promo
+	discount := 0
+	switch promo.Kind {
+	case model.PromoPercentOff:
+		discount = int(math.Round(float64(quote.Price) * float64(promo.Percent) / 100))
+	case model.PromoFixedOff:
+		discount = promo.Amount
+	case model.PromoFreeFirstHour:
+		stay := end.Sub(start)
+		switch {
+		case stay <= time.Hour:
+			discount = quote.Price
+		case quote.Product != nil:
+			discount = int(math.Round(float64(quote.Product.Price) * float64(time.Hour) / float64(stay)))
+		default:
+			// the first hour in a gap of the stay isn't charged, so there is no discount for it
+			discount = quote.firstHour
+		}
+	}
+
+	if discount > quote.Price {
+		discount = quote.Price
+	}
+	return discount
+}
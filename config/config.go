@@ -18,3 +18,22 @@ func GetSqliteConfig(dbName string) *DBConfig {
 		GormConfig:  gorm.Config{},
 	}
 }
+
+// PricingConfig contains the pricing engine configuration
+type PricingConfig struct {
+	// Policy is the name of the policy combining the priced segments of a stay: strict, sum or max
+	Policy string
+	// SearchParallelism is the most facilities quoted at the same time by the search
+	SearchParallelism int
+	// ExchangeRatesFile is the json file of the exchange rates converting the prices to other currencies
+	ExchangeRatesFile string
+}
+
+// GetPricingConfig get the pricing config
+func GetPricingConfig(policy string, searchParallelism int, exchangeRatesFile string) *PricingConfig {
+	return &PricingConfig{
+		Policy:            policy,
+		SearchParallelism: searchParallelism,
+		ExchangeRatesFile: exchangeRatesFile,
+	}
+}
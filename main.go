@@ -10,12 +10,19 @@ import (
 const(
 	// AppPort default port of the app
 	AppPort = 5000
+	// PricingPolicy default policy combining the priced segments of a stay
+	PricingPolicy = "strict"
+	// SearchParallelism default number of facilities quoted at the same time by the search
+	SearchParallelism = 8
+	// ExchangeRatesFile default file of the exchange rates converting the prices to other currencies
+	ExchangeRatesFile = "exchange_rates.json"
 )
 
 // main method of the app
 func main() {
 	spotHeroApp := &app.App{}
 	dbConfig := config.GetSqliteConfig("./rates.db")
-	spotHeroApp.Initialize(dbConfig)
+	pricingConfig := config.GetPricingConfig(PricingPolicy, SearchParallelism, ExchangeRatesFile)
+	spotHeroApp.Initialize(dbConfig, pricingConfig)
 	spotHeroApp.Run(fmt.Sprintf(":%d",AppPort))
 }
\ No newline at end of file